@@ -0,0 +1,157 @@
+// Package gomodrun is the forgotten go tool that executes and caches binaries included in go.mod files.
+// This makes it easy to version cli tools in your projects such as `golangci-lint`
+// and `ginkgo` that are versioned locked to what you specify in `go.mod`.
+// Binaries are cached by go version and package version.
+package gomodrun
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+)
+
+// ToolChange describes the changes made when adding or removing a tool.
+type ToolChange struct {
+	ToolsFile        string   // Path to the tools file, the first one changed when a tool is removed from several.
+	ToolsFileCreated bool     // True when the tools file was created.
+	ImportPath       string   // Package imported in the tools file.
+	Module           string   // Module required in go.mod.
+	Version          string   // Module version required in go.mod.
+	ModuleRemoved    bool     // True when the module is no longer required in go.mod.
+	CachedBins       []string // Binaries built or removed from .gomodrun.
+}
+
+// AddOptions contains parameters that change how Add declares a tool.
+type AddOptions struct {
+	Build bool // Build and cache the binary after adding it.
+}
+
+// Add declares a tool by adding a blank import of pkg to the tools file, creating the tools file if it's missing, and
+// requiring its module in go.mod and go.sum. pkg may include a version such as github.com/golang/mock/mockgen@v1.6.0,
+// otherwise the latest version is used.
+func Add(pkgRoot, pkg string, options *AddOptions) (*ToolChange, error) {
+	runner, err := NewRunner(&Options{PkgRoot: pkgRoot})
+	if err != nil {
+		return nil, err
+	}
+
+	return runner.Add(pkg, options)
+}
+
+// Add declares a tool by adding a blank import of pkg to the tools file, creating the tools file if it's missing, and
+// requiring its module in go.mod and go.sum. Only pkg's module and its dependencies change in go.mod, other requires
+// are left as they are.
+func (r *Runner) Add(pkg string, options *AddOptions) (*ToolChange, error) {
+	if options == nil {
+		options = &AddOptions{}
+	}
+
+	pkgRoot := r.PkgRoot
+	change := &ToolChange{
+		ImportPath: strings.SplitN(pkg, "@", 2)[0],
+		CachedBins: []string{},
+	}
+
+	var err error
+	change.ToolsFile, err = getToolsFile(pkgRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	var original []byte
+	var src []byte
+	if change.ToolsFile == "" {
+		change.ToolsFile = path.Join(pkgRoot, "tools.go")
+		change.ToolsFileCreated = true
+		if _, err = os.Stat(change.ToolsFile); !os.IsNotExist(err) {
+			return nil, fmt.Errorf("%s exists but is not a tools file", change.ToolsFile)
+		}
+
+		pkgName, err := getToolsPkgName(pkgRoot)
+		if err != nil {
+			return nil, err
+		}
+
+		config, err := r.loadConfig()
+		if err != nil {
+			return nil, err
+		}
+
+		src, err = newToolsFile(pkgName, config.Tags, []string{change.ImportPath})
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		original, err = ioutil.ReadFile(change.ToolsFile)
+		if err != nil {
+			return nil, err
+		}
+
+		src, err = addToolImport(original, change.ImportPath)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	snapshot, err := getModSnapshot(pkgRoot, change.ToolsFile)
+	if err != nil {
+		return nil, err
+	}
+
+	err = ioutil.WriteFile(change.ToolsFile, src, 0o644) //nolint:gosec // Source files are world readable.
+	if err != nil {
+		return nil, err
+	}
+
+	err = r.requireTool(change, pkg, options)
+	if err != nil {
+		// Restore the tools file, go.mod and go.sum so a failed add leaves the project untouched.
+		snapshot.restore()
+		return nil, err
+	}
+
+	return change, nil
+}
+
+// requireTool requires pkg's module in go.mod, building the tool when options.Build is set.
+func (r *Runner) requireTool(change *ToolChange, pkg string, options *AddOptions) error {
+	err := r.runGo("get", pkg)
+	if err != nil {
+		return err
+	}
+
+	mod, err := getGoMod(r.PkgRoot)
+	if err != nil {
+		return err
+	}
+
+	req := getRequire(mod, change.ImportPath)
+	if req == nil {
+		return fmt.Errorf("cant find require for module %s in go.mod", change.ImportPath)
+	}
+	change.Module = req.Mod.Path
+	change.Version = req.Mod.Version
+
+	// go get marks the module indirect as the tools file is behind a build tag. Requiring it again only clears the
+	// marker on this module, where go mod tidy would rewrite every require.
+	if req.Indirect {
+		err = r.runGo("mod", "edit", "-droprequire="+req.Mod.Path, "-require="+req.Mod.Path+"@"+req.Mod.Version)
+		if err != nil {
+			return err
+		}
+	}
+
+	if !options.Build {
+		return nil
+	}
+
+	cachedBin, err := r.Build(getBinName(change.ImportPath))
+	if err != nil {
+		return err
+	}
+	change.CachedBins = append(change.CachedBins, cachedBin)
+
+	return nil
+}
@@ -0,0 +1,171 @@
+package gomodrun
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// failingBuildToolchain runs go commands with Toolchain except for go build, which fails.
+type failingBuildToolchain struct {
+	Toolchain
+}
+
+func (t *failingBuildToolchain) Run(dir string, output io.Writer, args ...string) error {
+	if args[0] == "build" {
+		return errors.New("exit status 1")
+	}
+
+	return t.Toolchain.Run(dir, output, args...)
+}
+
+var _ = Describe("add", func() {
+	var tempDir string
+	var goProxy string
+
+	BeforeEach(func() {
+		// go-hello-world-test is already in the module cache as it's required by gomodrun's go.mod.
+		goProxy = os.Getenv("GOPROXY")
+		err := os.Setenv("GOPROXY", "off")
+		if err != nil {
+			panic(err)
+		}
+
+		tempDir, err = ioutil.TempDir(os.TempDir(), "gomodrun-add")
+		if err != nil {
+			panic(err)
+		}
+
+		err = ioutil.WriteFile(path.Join(tempDir, "go.mod"), []byte("module github.com/dustinblackman/gomodrun-test\n\ngo 1.13\n"), 0600)
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	AfterEach(func() {
+		err := os.Setenv("GOPROXY", goProxy)
+		if err != nil {
+			panic(err)
+		}
+
+		err = os.RemoveAll(tempDir)
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	It("should create the tools file, require the module and build the binary", func() {
+		change, err := Add(tempDir, "github.com/dustinblackman/go-hello-world-test/hello-world@v0.0.2", &AddOptions{Build: true})
+		Expect(err).To(BeNil())
+		Expect(change.ToolsFile).To(Equal(path.Join(tempDir, "tools.go")))
+		Expect(change.ToolsFileCreated).To(BeTrue())
+		Expect(change.Module).To(Equal("github.com/dustinblackman/go-hello-world-test"))
+		Expect(change.Version).To(Equal("v0.0.2"))
+		Expect(change.CachedBins).To(HaveLen(1))
+
+		content, err := ioutil.ReadFile(change.ToolsFile)
+		Expect(err).To(BeNil())
+		Expect(string(content)).To(HavePrefix("//go:build tools\n// +build tools\n"))
+		Expect(string(content)).To(ContainSubstring(`_ "github.com/dustinblackman/go-hello-world-test/hello-world"`))
+
+		mod, err := getGoMod(tempDir)
+		Expect(err).To(BeNil())
+		Expect(getRequire(mod, change.ImportPath).Mod.Version).To(Equal("v0.0.2"))
+
+		_, err = os.Stat(change.CachedBins[0])
+		Expect(err).To(BeNil())
+	})
+
+	It("should add to an existing tools file", func() {
+		toolsFile := path.Join(tempDir, "go.tools.go")
+		err := ioutil.WriteFile(toolsFile, []byte("// +build tools\n\npackage gomodrun\n"), 0600)
+		Expect(err).To(BeNil())
+
+		change, err := Add(tempDir, "github.com/dustinblackman/go-hello-world-test/hello-world@v0.0.2", &AddOptions{})
+		Expect(err).To(BeNil())
+		Expect(change.ToolsFile).To(Equal(toolsFile))
+		Expect(change.ToolsFileCreated).To(BeFalse())
+		Expect(change.CachedBins).To(BeEmpty())
+
+		binNames, err := GetToolBinNames(tempDir)
+		Expect(err).To(BeNil())
+		Expect(binNames).To(Equal([]string{"hello-world"}))
+	})
+
+	It("should leave the tools file untouched when the module can not be found", func() {
+		_, err := Add(tempDir, "github.com/dustinblackman/not-real/not-real@v0.0.1", &AddOptions{})
+		Expect(err).ToNot(BeNil())
+
+		_, err = os.Stat(path.Join(tempDir, "tools.go"))
+		Expect(os.IsNotExist(err)).To(BeTrue())
+	})
+
+	It("should restore go.mod and go.sum when building the tool fails", func() {
+		goMod, err := ioutil.ReadFile(path.Join(tempDir, "go.mod"))
+		Expect(err).To(BeNil())
+
+		runner, err := NewRunner(&Options{PkgRoot: tempDir})
+		Expect(err).To(BeNil())
+		runner.Toolchain = &failingBuildToolchain{Toolchain: runner.toolchain()}
+		runner.CacheDir = path.Join(tempDir, "cache")
+
+		_, err = runner.Add("github.com/dustinblackman/go-hello-world-test/hello-world@v0.0.2", &AddOptions{Build: true})
+		Expect(err).To(MatchError(ContainSubstring("building hello-world failed")))
+
+		content, err := ioutil.ReadFile(path.Join(tempDir, "go.mod"))
+		Expect(err).To(BeNil())
+		Expect(string(content)).To(Equal(string(goMod)))
+
+		_, err = os.Stat(path.Join(tempDir, "go.sum"))
+		Expect(os.IsNotExist(err)).To(BeTrue())
+		_, err = os.Stat(path.Join(tempDir, "tools.go"))
+		Expect(os.IsNotExist(err)).To(BeTrue())
+	})
+
+	It("should build with the runner's cache directory", func() {
+		runner, err := NewRunner(&Options{PkgRoot: tempDir})
+		Expect(err).To(BeNil())
+		runner.CacheDir = path.Join(tempDir, "cache")
+
+		change, err := runner.Add("github.com/dustinblackman/go-hello-world-test/hello-world@v0.0.2", &AddOptions{Build: true})
+		Expect(err).To(BeNil())
+		Expect(change.CachedBins).To(HaveLen(1))
+		Expect(change.CachedBins[0]).To(HavePrefix(path.Join(tempDir, "cache")))
+	})
+
+	It("should only change the added module's require", func() {
+		err := ioutil.WriteFile(path.Join(tempDir, "go.mod"), []byte("module github.com/dustinblackman/gomodrun-test\n\ngo 1.13\n\nrequire github.com/onsi/ginkgo v1.16.5\n"), 0600)
+		Expect(err).To(BeNil())
+
+		_, err = Add(tempDir, "github.com/dustinblackman/go-hello-world-test/hello-world@v0.0.2", nil)
+		Expect(err).To(BeNil())
+
+		mod, err := getGoMod(tempDir)
+		Expect(err).To(BeNil())
+		Expect(getRequire(mod, "github.com/onsi/ginkgo")).ToNot(BeNil())
+		req := getRequire(mod, "github.com/dustinblackman/go-hello-world-test")
+		Expect(req.Mod.Version).To(Equal("v0.0.2"))
+		Expect(req.Indirect).To(BeFalse())
+	})
+
+	It("should create the tools file with the build tags in .gomodrun.yml", func() {
+		err := ioutil.WriteFile(path.Join(tempDir, ConfigFileName), []byte("tags:\n  - tooling\n"), 0600)
+		Expect(err).To(BeNil())
+
+		change, err := Add(tempDir, "github.com/dustinblackman/go-hello-world-test/hello-world@v0.0.2", &AddOptions{})
+		Expect(err).To(BeNil())
+
+		content, err := ioutil.ReadFile(change.ToolsFile)
+		Expect(err).To(BeNil())
+		Expect(string(content)).To(HavePrefix("//go:build tooling\n// +build tooling\n"))
+
+		binNames, err := GetToolBinNames(tempDir)
+		Expect(err).To(BeNil())
+		Expect(binNames).To(Equal([]string{"hello-world"}))
+	})
+})
@@ -0,0 +1,228 @@
+// Package gomodrun is the forgotten go tool that executes and caches binaries included in go.mod files.
+// This makes it easy to version cli tools in your projects such as `golangci-lint`
+// and `ginkgo` that are versioned locked to what you specify in `go.mod`.
+// Binaries are cached by go version and package version.
+package gomodrun
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// Invocation is a single run of a tool in a batch.
+type Invocation struct {
+	BinName string   // Name of the tool to run.
+	Args    []string // Arguments passed to the tool.
+	Dir     string   // Working directory of the tool. Defaults to Options.Dir.
+}
+
+// String returns the invocation as it would be written in a batch file.
+func (i *Invocation) String() string {
+	words := append([]string{i.BinName}, i.Args...)
+	if i.Dir != "" {
+		words = append([]string{"-C", i.Dir}, words...)
+	}
+
+	return strings.Join(words, " ")
+}
+
+// BatchResult is the outcome of a single invocation in a batch.
+type BatchResult struct {
+	Invocation Invocation // Invocation that was run.
+	Stdout     []byte     // Everything the tool wrote to stdout.
+	Stderr     []byte     // Everything the tool wrote to stderr.
+	ExitCode   int        // Exit code of the tool, -1 when it couldn't be run.
+	Err        error      // Error resolving, building or running the tool.
+}
+
+// splitWords splits a batch line in to words, supporting single quotes, double quotes and backslash escapes.
+func splitWords(line string) ([]string, error) {
+	words := []string{}
+	word := strings.Builder{}
+	inWord := false
+	var quote rune
+	escaped := false
+
+	for _, char := range line {
+		switch {
+		case escaped:
+			word.WriteRune(char)
+			escaped = false
+		case char == '\\' && quote != '\'':
+			escaped = true
+			inWord = true
+		case quote != 0 && char == quote:
+			quote = 0
+		case quote != 0:
+			word.WriteRune(char)
+		case char == '\'' || char == '"':
+			quote = char
+			inWord = true
+		case char == ' ' || char == '\t':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(char)
+			inWord = true
+		}
+	}
+
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated %c quote", quote)
+	}
+
+	if inWord || escaped {
+		words = append(words, word.String())
+	}
+
+	return words, nil
+}
+
+// ParseBatch reads invocations from reader, one per line in the form `[-C dir] cli-name [parameters]`. Blank lines
+// and lines starting with # are skipped.
+func ParseBatch(reader io.Reader) ([]Invocation, error) {
+	invocations := []Invocation{}
+	scanner := bufio.NewScanner(reader)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		words, err := splitWords(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s", lineNumber, err)
+		}
+
+		invocation := Invocation{}
+		if words[0] == "-C" {
+			if len(words) < 3 {
+				return nil, fmt.Errorf("line %d: -C requires a directory and a cli-name", lineNumber)
+			}
+			invocation.Dir = words[1]
+			words = words[2:]
+		}
+
+		invocation.BinName = words[0]
+		invocation.Args = words[1:]
+		invocations = append(invocations, invocation)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return invocations, nil
+}
+
+// cachedToolchain looks up the go version once, sharing it between copies of a Runner.
+type cachedToolchain struct {
+	Toolchain
+	once    sync.Once
+	version string
+	err     error
+}
+
+func (t *cachedToolchain) Version() (string, error) {
+	t.once.Do(func() {
+		t.version, t.err = t.Toolchain.Version()
+	})
+
+	return t.version, t.err
+}
+
+// RunBatch runs every invocation, resolving and building each tool once and running at most parallel invocations at
+// a time. Parallel defaults to the number of CPUs.
+func RunBatch(invocations []Invocation, parallel int, options *Options) ([]BatchResult, error) {
+	runner, err := NewRunner(options)
+	if err != nil {
+		return nil, err
+	}
+
+	return runner.RunBatch(invocations, parallel), nil
+}
+
+// RunBatch runs every invocation, resolving and building each tool once and running at most parallel invocations at
+// a time. Each tool's output is captured in its result, returned in the same order as invocations. Hook callbacks
+// and Cache may be called from multiple goroutines.
+func (r *Runner) RunBatch(invocations []Invocation, parallel int) []BatchResult {
+	if parallel < 1 {
+		parallel = runtime.NumCPU()
+	}
+
+	runner := *r
+	runner.Toolchain = &cachedToolchain{Toolchain: r.toolchain()}
+	runner.Stdin = nil
+
+	// Every tool is built before the invocations fan out, so local overrides, which are always rebuilt, aren't built
+	// into the same path by several invocations at once.
+	resolutions := map[string]*Resolution{}
+	cachedBins := map[string]string{}
+	errs := map[string]error{}
+	for _, invocation := range invocations {
+		if _, ok := resolutions[invocation.BinName]; ok || errs[invocation.BinName] != nil {
+			continue
+		}
+
+		resolution, err := runner.resolve(invocation.BinName, false)
+		if err == nil {
+			cachedBins[invocation.BinName], err = runner.ensureBin(resolution, nil)
+		}
+
+		if err != nil {
+			errs[invocation.BinName] = err
+			continue
+		}
+		resolutions[invocation.BinName] = resolution
+	}
+
+	results := make([]BatchResult, len(invocations))
+	slots := make(chan bool, parallel)
+	wg := sync.WaitGroup{}
+	for idx, invocation := range invocations {
+		results[idx] = BatchResult{
+			Invocation: invocation,
+			ExitCode:   -1,
+			Err:        errs[invocation.BinName],
+		}
+		if results[idx].Err != nil {
+			continue
+		}
+
+		wg.Add(1)
+		slots <- true
+		go func(result *BatchResult) {
+			defer func() {
+				<-slots
+				wg.Done()
+			}()
+
+			stdout := &bytes.Buffer{}
+			stderr := &bytes.Buffer{}
+			invocationRunner := runner
+			invocationRunner.Stdout = stdout
+			invocationRunner.Stderr = stderr
+			if result.Invocation.Dir != "" {
+				invocationRunner.Dir = result.Invocation.Dir
+			}
+
+			binName := result.Invocation.BinName
+			result.ExitCode, result.Err = invocationRunner.runBin(resolutions[binName], cachedBins[binName], result.Invocation.Args)
+			result.Stdout = stdout.Bytes()
+			result.Stderr = stderr.Bytes()
+		}(&results[idx])
+	}
+	wg.Wait()
+
+	return results
+}
@@ -0,0 +1,118 @@
+package gomodrun
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("batch", func() {
+	Context("ParseBatch", func() {
+		It("should parse invocations with quotes, directories and comments", func() {
+			invocations, err := ParseBatch(strings.NewReader(`# generate mocks
+mockgen -source=a.go -destination="mocks/a b.go"
+
+-C ./pkg/api mockgen 'single quoted' escaped\ space
+`))
+			Expect(err).To(BeNil())
+			Expect(invocations).To(Equal([]Invocation{
+				{BinName: "mockgen", Args: []string{"-source=a.go", "-destination=mocks/a b.go"}},
+				{BinName: "mockgen", Args: []string{"single quoted", "escaped space"}, Dir: "./pkg/api"},
+			}))
+		})
+
+		It("should return an error for unterminated quotes", func() {
+			_, err := ParseBatch(strings.NewReader("mockgen\nmockgen \"a\n"))
+			Expect(err).To(MatchError("line 2: unterminated \" quote"))
+		})
+
+		It("should return an error when -C is missing a cli-name", func() {
+			_, err := ParseBatch(strings.NewReader("-C ./pkg\n"))
+			Expect(err).To(MatchError("line 1: -C requires a directory and a cli-name"))
+		})
+	})
+
+	Context("RunBatch", func() {
+		var tempDir string
+		var toolchain *fakeToolchain
+		var runner *Runner
+
+		BeforeEach(func() {
+			tempDir = createProjectFixture()
+			srcDir := path.Join(tempDir, "src")
+			err := os.MkdirAll(srcDir, 0750)
+			Expect(err).To(BeNil())
+			err = ioutil.WriteFile(path.Join(srcDir, "go.mod"), []byte("module example.com/hello-world\n"), 0600)
+			Expect(err).To(BeNil())
+
+			toolchain = &fakeToolchain{script: `echo "$1"; echo "err $1" >&2; exit "$2"`}
+			runner, err = NewRunner(&Options{PkgRoot: tempDir})
+			Expect(err).To(BeNil())
+			runner.Toolchain = toolchain
+			runner.Sources = &fakeSources{dir: srcDir}
+			runner.Cache = &fakeCache{dir: path.Join(tempDir, "cache")}
+		})
+
+		AfterEach(func() {
+			err := os.RemoveAll(tempDir)
+			if err != nil {
+				panic(err)
+			}
+		})
+
+		It("should build each tool once and keep each invocation's output and exit code", func() {
+			invocations := []Invocation{}
+			for _, args := range [][]string{{"one", "0"}, {"two", "2"}, {"three", "0"}, {"four", "0"}} {
+				invocations = append(invocations, Invocation{BinName: "hello-world", Args: args})
+			}
+			invocations = append(invocations, Invocation{BinName: "missing"})
+
+			results := runner.RunBatch(invocations, 2)
+			Expect(results).To(HaveLen(5))
+			Expect(toolchain.calls).To(HaveLen(1))
+			Expect(toolchain.versions).To(Equal(1))
+
+			for idx, name := range []string{"one", "two", "three", "four"} {
+				Expect(results[idx].Err).To(BeNil())
+				Expect(string(results[idx].Stdout)).To(Equal(name + "\n"))
+				Expect(string(results[idx].Stderr)).To(Equal("err " + name + "\n"))
+			}
+			Expect(results[0].ExitCode).To(Equal(0))
+			Expect(results[1].ExitCode).To(Equal(2))
+
+			Expect(results[4].Err).To(MatchError("cant find bin missing in tools file"))
+			Expect(results[4].ExitCode).To(Equal(-1))
+		})
+
+		It("should build local overrides once before running invocations in parallel", func() {
+			forkDir := path.Join(tempDir, "fork")
+			err := os.MkdirAll(path.Join(forkDir, "hello-world"), 0750)
+			Expect(err).To(BeNil())
+			runner.Overrides = map[string]string{"hello-world": forkDir}
+
+			invocations := []Invocation{}
+			for _, args := range [][]string{{"one", "0"}, {"two", "0"}, {"three", "0"}, {"four", "0"}} {
+				invocations = append(invocations, Invocation{BinName: "hello-world", Args: args})
+			}
+
+			results := runner.RunBatch(invocations, 4)
+			for _, result := range results {
+				Expect(result.Err).To(BeNil())
+				Expect(result.ExitCode).To(Equal(0))
+			}
+			Expect(toolchain.calls).To(HaveLen(1))
+		})
+
+		It("should run invocations in their directory", func() {
+			toolchain.script = "pwd"
+			dir := path.Join(tempDir, "src")
+			results := runner.RunBatch([]Invocation{{BinName: "hello-world", Dir: dir}}, 0)
+			Expect(results[0].Err).To(BeNil())
+			Expect(strings.TrimSpace(string(results[0].Stdout))).To(HaveSuffix("src"))
+		})
+	})
+})
@@ -0,0 +1,102 @@
+// Package gomodrun is the forgotten go tool that executes and caches binaries included in go.mod files.
+// This makes it easy to version cli tools in your projects such as `golangci-lint`
+// and `ginkgo` that are versioned locked to what you specify in `go.mod`.
+// Binaries are cached by go version and package version.
+package gomodrun
+
+import (
+	"os"
+	"strings"
+)
+
+// buildLogExt is the extension of the build log kept next to each cached binary.
+const buildLogExt = ".log"
+
+// buildLogTailLines is how many lines from the end of a failed build's log are included in its error.
+const buildLogTailLines = 20
+
+// getBuildLogPath returns the path of the build log for a cached binary.
+func getBuildLogPath(binPath string) string {
+	return strings.TrimSuffix(binPath, ".exe") + buildLogExt
+}
+
+func isBuildLog(filePath string) bool {
+	return strings.HasSuffix(filePath, buildLogExt)
+}
+
+// completionMarkerExt is the extension of the file kept next to a cached binary recording whether it supports Cobra
+// completions.
+const completionMarkerExt = ".complete"
+
+// getCompletionMarkerPath returns the path of the completion marker for a cached binary.
+func getCompletionMarkerPath(binPath string) string {
+	return strings.TrimSuffix(binPath, ".exe") + completionMarkerExt
+}
+
+// trimmedMarkerExt is the extension of the file kept next to a cached binary once it's known to be built with
+// -trimpath.
+const trimmedMarkerExt = ".trimmed"
+
+// getTrimmedMarkerPath returns the path of the -trimpath marker for a cached binary.
+func getTrimmedMarkerPath(binPath string) string {
+	return strings.TrimSuffix(binPath, ".exe") + trimmedMarkerExt
+}
+
+// getSidecarPaths returns the files kept next to a cached binary, which are cleaned up along with it.
+func getSidecarPaths(binPath string) []string {
+	return []string{getBuildLogPath(binPath), getCompletionMarkerPath(binPath), getTrimmedMarkerPath(binPath)}
+}
+
+// isSidecar returns true for files kept next to a cached binary rather than binaries themselves.
+func isSidecar(filePath string) bool {
+	return isBuildLog(filePath) || strings.HasSuffix(filePath, completionMarkerExt) ||
+		strings.HasSuffix(filePath, trimmedMarkerExt)
+}
+
+// getLogTail returns the last lines of a build log, which is where go reports what failed.
+func getLogTail(output string) string {
+	lines := strings.Split(strings.TrimRight(output, "\n"), "\n")
+	if len(lines) > buildLogTailLines {
+		lines = lines[len(lines)-buildLogTailLines:]
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// getCacheEntrySize returns the size of a cached binary along with its build log and completion marker.
+func getCacheEntrySize(binPath string) (int64, error) {
+	info, err := os.Stat(binPath)
+	if err != nil {
+		return 0, err
+	}
+
+	size := info.Size()
+	for _, sidecarPath := range getSidecarPaths(binPath) {
+		if sidecarPath == binPath {
+			continue
+		}
+
+		if sidecarInfo, err := os.Stat(sidecarPath); err == nil {
+			size += sidecarInfo.Size()
+		}
+	}
+
+	return size, nil
+}
+
+// removeCacheEntry removes a cached binary along with its build log and completion marker.
+func removeCacheEntry(binPath string) error {
+	err := os.Remove(binPath)
+	if err != nil {
+		return err
+	}
+
+	for _, sidecarPath := range getSidecarPaths(binPath) {
+		err = os.Remove(sidecarPath)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,124 @@
+package gomodrun
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("buildlog", func() {
+	var tempDir string
+	var goVersion string
+	var baseDir string
+
+	BeforeEach(func() {
+		var err error
+		goVersion, err = getGoVersion()
+		if err != nil {
+			panic(err)
+		}
+
+		tempDir = createProjectFixture()
+		baseDir = path.Join(tempDir, ".gomodrun", goVersion)
+	})
+
+	AfterEach(func() {
+		err := os.RemoveAll(tempDir)
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	It("should log the build next to the binary and stream its output", func() {
+		progress := &bytes.Buffer{}
+		buildOutput := &bytes.Buffer{}
+		exitCode, err := Run("hello-world", []string{}, &Options{
+			PkgRoot:     tempDir,
+			Stdout:      ioutil.Discard,
+			Progress:    progress,
+			BuildOutput: buildOutput,
+		})
+		Expect(err).To(BeNil())
+		Expect(exitCode).To(Equal(0))
+
+		cachedBin := path.Join(baseDir, "github.com/dustinblackman/go-hello-world-test@v0.0.2/hello-world/hello-world")
+		buildLog, err := ioutil.ReadFile(cachedBin + ".log")
+		Expect(err).To(BeNil())
+		Expect(string(buildLog)).To(ContainSubstring("$ go build -trimpath -o " + cachedBin))
+		Expect(buildOutput.String()).To(Equal(string(buildLog)))
+		Expect(progress.String()).To(Equal("building hello-world@v0.0.2 with " + goVersion + "…\n"))
+	})
+
+	It("should keep build logs out of the binaries unless the build failed", func() {
+		addTidyBins(tempDir, goVersion)
+		keptBin := path.Join(baseDir, "github.com/dustinblackman/go-hello-world-test@v0.0.2/hello-world/hello-world")
+		orphanLog := path.Join(baseDir, "github.com/dustinblackman/go-hello-world-test@v0.0.3/hello-world/hello-world.log")
+		err := ioutil.WriteFile(keptBin+".log", []byte("log"), 0600)
+		Expect(err).To(BeNil())
+		err = os.MkdirAll(path.Dir(orphanLog), 0750)
+		Expect(err).To(BeNil())
+		err = ioutil.WriteFile(orphanLog, []byte("log"), 0600)
+		Expect(err).To(BeNil())
+
+		binPaths, err := getAllBins(baseDir)
+		Expect(err).To(BeNil())
+		Expect(binPaths).To(ConsistOf(
+			keptBin,
+			path.Join(baseDir, "github.com/dustinblackman/go-hello-world-test@v0.0.1/hello-world/hello-world"),
+			orphanLog,
+		))
+
+		size, err := getCacheEntrySize(keptBin)
+		Expect(err).To(BeNil())
+		Expect(size).To(Equal(int64(6)))
+	})
+
+	It("should remove build logs along with their binaries", func() {
+		addTidyBins(tempDir, goVersion)
+		removedBin := path.Join(baseDir, "github.com/dustinblackman/go-hello-world-test@v0.0.1/hello-world/hello-world")
+		err := ioutil.WriteFile(removedBin+".log", []byte("log"), 0600)
+		Expect(err).To(BeNil())
+
+		report, err := TidyWithReport(tempDir, &TidyOptions{})
+		Expect(err).To(BeNil())
+		Expect(report.Removed).To(ContainElement(TidyEntry{
+			Path:   removedBin,
+			Size:   6,
+			Reason: "module version is no longer required by the tools file and go.mod",
+		}))
+
+		_, existsErr := os.Stat(removedBin + ".log")
+		Expect(os.IsNotExist(existsErr)).To(BeTrue())
+	})
+
+	It("should keep completion markers with their binaries", func() {
+		addTidyBins(tempDir, goVersion)
+		keptBin := path.Join(baseDir, "github.com/dustinblackman/go-hello-world-test@v0.0.2/hello-world/hello-world")
+		err := ioutil.WriteFile(getCompletionMarkerPath(keptBin), []byte("false\n"), 0600)
+		Expect(err).To(BeNil())
+
+		binPaths, err := getAllBins(baseDir)
+		Expect(err).To(BeNil())
+		Expect(binPaths).ToNot(ContainElement(getCompletionMarkerPath(keptBin)))
+
+		err = removeCacheEntry(keptBin)
+		Expect(err).To(BeNil())
+		_, err = os.Stat(getCompletionMarkerPath(keptBin))
+		Expect(os.IsNotExist(err)).To(BeTrue())
+	})
+
+	It("should limit build log tails", func() {
+		lines := []string{}
+		for idx := 0; idx < buildLogTailLines*2; idx++ {
+			lines = append(lines, "line")
+		}
+
+		Expect(strings.Count(getLogTail(strings.Join(lines, "\n")+"\n"), "\n")).To(Equal(buildLogTailLines - 1))
+		Expect(getLogTail("one\ntwo\n")).To(Equal("one\ntwo"))
+	})
+})
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"errors"
+	"flag"
+
+	"github.com/dustinblackman/gomodrun"
+)
+
+func runAdd(flags *flag.FlagSet) func(pkgRoot string, args []string) {
+	options := &gomodrun.AddOptions{}
+	flags.BoolVar(&options.Build, "build", false, "Builds the binaries for the added tools.")
+
+	return func(pkgRoot string, args []string) {
+		if len(args) == 0 {
+			exitWithError(errors.New("no package provided to add"))
+		}
+
+		for _, pkg := range args {
+			change, err := gomodrun.Add(pkgRoot, pkg, options)
+			if err != nil {
+				exitWithError(err)
+			}
+
+			if change.ToolsFileCreated {
+				printf("created %s\n", change.ToolsFile)
+			}
+			printf("added %s to %s\n", change.ImportPath, change.ToolsFile)
+			printf("required %s %s in go.mod\n", change.Module, change.Version)
+			for _, cachedBin := range change.CachedBins {
+				printf("built %s\n", cachedBin)
+			}
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/dustinblackman/gomodrun"
+)
+
+func runBatch(flags *flag.FlagSet) func(pkgRoot string, args []string) {
+	parallel := flags.Int("parallel", 0, "Runs at most this `count` of invocations at a time. Defaults to the number of CPUs.")
+
+	return func(pkgRoot string, args []string) {
+		if len(args) > 1 {
+			exitWithError(errors.New("batch takes at most one file"))
+		}
+
+		var reader io.Reader = os.Stdin
+		if len(args) == 1 && args[0] != "-" {
+			file, err := os.Open(args[0])
+			if err != nil {
+				exitWithError(err)
+			}
+			defer file.Close()
+			reader = file
+		}
+
+		invocations, err := gomodrun.ParseBatch(reader)
+		if err != nil {
+			exitWithError(err)
+		}
+
+		options := &gomodrun.Options{
+			Stderr:  os.Stderr,
+			Env:     os.Environ(),
+			PkgRoot: pkgRoot,
+			Logger:  logger,
+
+			Overrides: overrides,
+		}
+		if buildOutput {
+			options.BuildOutput = os.Stderr
+		}
+
+		results, err := gomodrun.RunBatch(invocations, *parallel, options)
+		if err != nil {
+			exitWithError(err)
+		}
+
+		exitCode := 0
+		for idx := range results {
+			result := &results[idx]
+			os.Stdout.Write(result.Stdout) //nolint // Ignore error, nothing to report it to.
+			os.Stderr.Write(result.Stderr) //nolint // Ignore error, nothing to report it to.
+
+			switch {
+			case result.Err != nil:
+				fmt.Fprintf(os.Stderr, "gomodrun: %s: %s\n", result.Invocation.String(), result.Err)
+			case result.ExitCode != 0:
+				fmt.Fprintf(os.Stderr, "gomodrun: %s exited with %d\n", result.Invocation.String(), result.ExitCode)
+			}
+
+			if exitCode == 0 && result.Err != nil {
+				exitCode = 1
+			} else if exitCode == 0 {
+				exitCode = result.ExitCode
+			}
+		}
+
+		os.Exit(exitCode)
+	}
+}
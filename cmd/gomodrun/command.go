@@ -0,0 +1,153 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+// flagAliases maps long flag names to their single letter forms.
+var flagAliases = map[string]string{
+	"pkg-root": "r",
+	"quiet":    "q",
+	"tidy":     "t",
+	"verbose":  "v",
+}
+
+// stringsFlag is a flag that can be repeated or given a comma separated list.
+type stringsFlag []string
+
+func (s *stringsFlag) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *stringsFlag) Set(value string) error {
+	*s = append(*s, strings.Split(value, ",")...)
+	return nil
+}
+
+type command struct {
+	name        string
+	usage       string // Arguments following the command name.
+	description string
+	passthrough bool                                                          // Stop parsing flags at the first argument, leaving the rest for a tool.
+	hidden      bool                                                          // Leave out of help and completions.
+	noFlags     bool                                                          // Pass every argument to the command, including flags.
+	setup       func(flags *flag.FlagSet) func(pkgRoot string, args []string) // Adds the command's flags, returning its run function.
+}
+
+// setupFlags returns the command's flags, including single letter aliases, along with its run function.
+func (cmd *command) setupFlags() (*flag.FlagSet, func(pkgRoot string, args []string)) {
+	flags := flag.NewFlagSet(cmd.name, flag.ContinueOnError)
+	flags.SetOutput(ioutil.Discard)
+	flags.Usage = func() {}
+
+	run := cmd.setup(flags)
+	for long, short := range flagAliases {
+		if f := flags.Lookup(long); f != nil {
+			flags.Var(f.Value, short, "")
+		}
+	}
+
+	return flags, run
+}
+
+func (cmd *command) run(pkgRoot string, args []string) {
+	flags, run := cmd.setupFlags()
+	run(pkgRoot, cmd.parse(flags, args))
+}
+
+func (cmd *command) printUsage(flags *flag.FlagSet) {
+	fmt.Printf("Usage:\n\tgomodrun [flags] %s %s\n\n%s\n", cmd.name, cmd.usage, cmd.description)
+
+	hasFlags := false
+	flags.VisitAll(func(f *flag.Flag) {
+		if f.Usage != "" {
+			hasFlags = true
+		}
+	})
+
+	if hasFlags {
+		fmt.Print("\nFlags:\n")
+		printFlags(flags)
+	}
+}
+
+// parse parses flags from anywhere in args, returning the remaining arguments. Flags are not parsed after `--`, or
+// after the first argument for passthrough commands, which keep the `--` so they can tell a tool from a command.
+// -h and --help print the command's usage and exit.
+func (cmd *command) parse(flags *flag.FlagSet, args []string) []string {
+	if cmd.noFlags {
+		return args
+	}
+
+	positional := []string{}
+	for {
+		err := flags.Parse(args)
+		if errors.Is(err, flag.ErrHelp) {
+			if cmd.name == "" {
+				printHelp(flags)
+			} else {
+				cmd.printUsage(flags)
+			}
+			os.Exit(0)
+		}
+
+		if err != nil {
+			exitWithError(fmt.Errorf("%s, see %s --help", err, strings.TrimSpace("gomodrun "+cmd.name)))
+		}
+
+		remaining := flags.Args()
+		consumed := len(args) - len(remaining)
+		separated := isSeparated(flags, args[:consumed])
+		if cmd.passthrough && separated {
+			return append([]string{"--"}, remaining...)
+		}
+
+		if cmd.passthrough || len(remaining) == 0 || separated {
+			return append(positional, remaining...)
+		}
+
+		positional = append(positional, remaining[0])
+		args = remaining[1:]
+	}
+}
+
+// isSeparated returns true when parsed flags were ended with `--`, rather than `--` being the value of a flag.
+func isSeparated(flags *flag.FlagSet, parsed []string) bool {
+	for idx := 0; idx < len(parsed); idx++ {
+		if parsed[idx] == "--" {
+			return true
+		}
+
+		name, _, hasValue := strings.Cut(strings.TrimLeft(parsed[idx], "-"), "=")
+		if f := flags.Lookup(name); f != nil && !hasValue && !isBoolFlag(f) {
+			idx++
+		}
+	}
+
+	return false
+}
+
+func printFlags(flags *flag.FlagSet) {
+	flags.VisitAll(func(f *flag.Flag) {
+		if f.Usage == "" {
+			return
+		}
+
+		name, usage := flag.UnquoteUsage(f)
+		line := "  "
+		if short, ok := flagAliases[f.Name]; ok {
+			line += "-" + short + ", "
+		}
+
+		line += "--" + f.Name
+		if name != "" {
+			line += " " + name
+		}
+		fmt.Printf("%s  %s\n", line, usage)
+	})
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"flag"
+
+	. "github.com/onsi/ginkgo"
+	"github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("command", func() {
+	Context("parse", func() {
+		type parsed struct {
+			name    string
+			enabled bool
+			args    []string
+		}
+
+		parse := func(cmd *command, args []string) parsed {
+			var name *string
+			var enabled *bool
+			cmd.setup = func(flags *flag.FlagSet) func(pkgRoot string, args []string) {
+				name = flags.String("name", "", "")
+				enabled = flags.Bool("enabled", false, "")
+				return nil
+			}
+
+			flags, _ := cmd.setupFlags()
+			remaining := cmd.parse(flags, args)
+			return parsed{name: *name, enabled: *enabled, args: remaining}
+		}
+
+		table.DescribeTable("should split flags from arguments",
+			func(cmd *command, args []string, expected parsed) {
+				Expect(parse(cmd, args)).To(Equal(expected))
+			},
+			table.Entry("flags before arguments", &command{name: "test"},
+				[]string{"--name", "value", "--enabled", "a", "b"},
+				parsed{name: "value", enabled: true, args: []string{"a", "b"}}),
+			table.Entry("flag=value", &command{name: "test"},
+				[]string{"--name=value", "a"},
+				parsed{name: "value", args: []string{"a"}}),
+			table.Entry("flags interspersed with arguments", &command{name: "test"},
+				[]string{"a", "--name=value", "b", "--enabled", "c"},
+				parsed{name: "value", enabled: true, args: []string{"a", "b", "c"}}),
+			table.Entry("-- ending flags", &command{name: "test"},
+				[]string{"a", "--", "--name=value", "b"},
+				parsed{args: []string{"a", "--name=value", "b"}}),
+			table.Entry("-- as a flag value", &command{name: "test"},
+				[]string{"--name", "--", "a", "--enabled"},
+				parsed{name: "--", enabled: true, args: []string{"a"}}),
+			table.Entry("passthrough stopping at the first argument", &command{name: "test", passthrough: true},
+				[]string{"--name", "value", "tool", "--enabled", "a"},
+				parsed{name: "value", args: []string{"tool", "--enabled", "a"}}),
+			table.Entry("passthrough keeping --", &command{name: "test", passthrough: true},
+				[]string{"--enabled", "--", "tool", "--name=value"},
+				parsed{enabled: true, args: []string{"--", "tool", "--name=value"}}),
+			table.Entry("no flags", &command{name: "test", noFlags: true},
+				[]string{"--name=value", "a"},
+				parsed{args: []string{"--name=value", "a"}}),
+			table.Entry("no arguments", &command{name: "test"},
+				[]string{},
+				parsed{args: []string{}}),
+		)
+	})
+})
@@ -0,0 +1,258 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/dustinblackman/gomodrun"
+)
+
+func runCompletion(flags *flag.FlagSet) func(pkgRoot string, args []string) {
+	return func(pkgRoot string, args []string) {
+		if len(args) > 1 {
+			exitWithError(errors.New("completion takes at most one shell"))
+		}
+
+		shell := getDefaultShell()
+		if len(args) > 0 {
+			shell = args[0]
+		}
+
+		script, err := gomodrun.GetCompletionScript(shell)
+		if err != nil {
+			exitWithError(err)
+		}
+
+		fmt.Print(script)
+	}
+}
+
+func runComplete(flags *flag.FlagSet) func(pkgRoot string, args []string) {
+	return func(pkgRoot string, args []string) {
+		if len(args) == 0 {
+			args = []string{""}
+		}
+
+		fmt.Print(complete(args))
+	}
+}
+
+func isBoolFlag(f *flag.Flag) bool {
+	boolFlag, ok := f.Value.(interface{ IsBoolFlag() bool })
+	return ok && boolFlag.IsBoolFlag()
+}
+
+// scanFlags sets the flags found in words and returns the remaining arguments, stopping at the first argument for
+// passthrough commands. When the last word is a flag waiting on its value, that flag is returned instead.
+func scanFlags(flags *flag.FlagSet, words []string, passthrough bool) ([]string, *flag.Flag, bool) {
+	positional := []string{}
+	for idx := 0; idx < len(words); idx++ {
+		word := words[idx]
+		if word == "--" {
+			return append(positional, words[idx+1:]...), nil, true
+		}
+
+		if !strings.HasPrefix(word, "-") || word == "-" {
+			if passthrough {
+				return append(positional, words[idx:]...), nil, false
+			}
+			positional = append(positional, word)
+			continue
+		}
+
+		name, value, hasValue := strings.Cut(strings.TrimLeft(word, "-"), "=")
+		f := flags.Lookup(name)
+		if f == nil || isBoolFlag(f) {
+			continue
+		}
+
+		if !hasValue {
+			if idx+1 == len(words) {
+				return positional, f, false
+			}
+			idx++
+			value = words[idx]
+		}
+		flags.Set(name, value) //nolint // Ignore error, an invalid value doesn't change what can be completed.
+	}
+
+	return positional, nil, false
+}
+
+func filterCandidates(candidates []string, toComplete string) []string {
+	filtered := []string{}
+	for _, candidate := range candidates {
+		if strings.HasPrefix(candidate, toComplete) {
+			filtered = append(filtered, candidate)
+		}
+	}
+
+	return filtered
+}
+
+func getFlagCompletion(flags *flag.FlagSet, toComplete string) *gomodrun.Completion {
+	name, value, hasValue := strings.Cut(strings.TrimLeft(toComplete, "-"), "=")
+	if f := flags.Lookup(name); f != nil && hasValue {
+		completion := getFlagValueCompletion(f, value)
+		for idx, candidate := range completion.Candidates {
+			completion.Candidates[idx] = strings.TrimSuffix(toComplete, value) + candidate
+		}
+
+		return completion
+	}
+
+	candidates := []string{}
+	flags.VisitAll(func(f *flag.Flag) {
+		if f.Usage == "" || !strings.HasPrefix("--"+f.Name, toComplete) {
+			return
+		}
+
+		_, usage := flag.UnquoteUsage(f)
+		candidates = append(candidates, "--"+f.Name+"\t"+usage)
+	})
+
+	return &gomodrun.Completion{
+		Candidates: candidates,
+		Directive:  gomodrun.CompDirectiveNoFileComp,
+	}
+}
+
+func getFlagValueCompletion(f *flag.Flag, toComplete string) *gomodrun.Completion {
+	switch f.Name {
+	case "pkg-root", "r", "cache-dir":
+		return &gomodrun.Completion{Directive: gomodrun.CompDirectiveFilterDirs}
+	case "preset":
+		presets := []string{}
+		for preset := range gomodrun.Presets {
+			presets = append(presets, preset)
+		}
+		sort.Strings(presets)
+
+		return &gomodrun.Completion{
+			Candidates: filterCandidates(presets, toComplete),
+			Directive:  gomodrun.CompDirectiveNoFileComp,
+		}
+	}
+
+	return &gomodrun.Completion{Directive: gomodrun.CompDirectiveNoFileComp}
+}
+
+func getCommandNames() []string {
+	names := []string{}
+	for _, cmd := range getCommands() {
+		if !cmd.hidden {
+			names = append(names, cmd.name)
+		}
+	}
+
+	return names
+}
+
+func getToolNames(pkgRoot string) []string {
+	if pkgRoot == "" {
+		var err error
+		pkgRoot, err = gomodrun.GetPkgRoot()
+		if err != nil {
+			return []string{}
+		}
+	}
+
+	binNames, err := gomodrun.GetToolBinNames(pkgRoot)
+	if err != nil {
+		return []string{}
+	}
+
+	return binNames
+}
+
+// completeTool delegates to the tool's own Cobra completions, falling back to completing files.
+func completeTool(pkgRoot, binName string, args []string) *gomodrun.Completion {
+	completion, err := gomodrun.CompleteTool(binName, args, &gomodrun.Options{PkgRoot: pkgRoot})
+	if err != nil || completion == nil {
+		return &gomodrun.Completion{Directive: gomodrun.CompDirectiveDefault}
+	}
+
+	return completion
+}
+
+// complete returns completions for the last of args, which follow gomodrun on the command line.
+func complete(args []string) *gomodrun.Completion {
+	toComplete := args[len(args)-1]
+	words := args[:len(args)-1]
+
+	// PowerShell passes "" for an empty word, as it drops empty arguments.
+	if toComplete == `""` {
+		toComplete = ""
+	}
+
+	rootFlags, _ := getRootCommand().setupFlags()
+	positional, valueFlag, afterSeparator := scanFlags(rootFlags, words, true)
+	if valueFlag != nil {
+		return getFlagValueCompletion(valueFlag, toComplete)
+	}
+	pkgRoot := rootFlags.Lookup("pkg-root").Value.String()
+
+	if len(positional) == 0 {
+		if strings.HasPrefix(toComplete, "-") && !afterSeparator {
+			return getFlagCompletion(rootFlags, toComplete)
+		}
+
+		candidates := getToolNames(pkgRoot)
+		if !afterSeparator {
+			candidates = append(getCommandNames(), append(candidates, "help")...)
+		}
+
+		return &gomodrun.Completion{
+			Candidates: filterCandidates(candidates, toComplete),
+			Directive:  gomodrun.CompDirectiveNoFileComp,
+		}
+	}
+
+	name := positional[0]
+	cmd := getCommand(name)
+	if afterSeparator || cmd == nil || cmd.hidden {
+		if name == "help" && !afterSeparator {
+			candidates := []string{}
+			if len(positional) == 1 {
+				candidates = filterCandidates(getCommandNames(), toComplete)
+			}
+			return &gomodrun.Completion{Candidates: candidates, Directive: gomodrun.CompDirectiveNoFileComp}
+		}
+
+		return completeTool(pkgRoot, name, append(positional[1:], toComplete))
+	}
+
+	cmdFlags, _ := cmd.setupFlags()
+	cmdArgs, valueFlag, afterSeparator := scanFlags(cmdFlags, positional[1:], cmd.passthrough)
+	if valueFlag != nil {
+		return getFlagValueCompletion(valueFlag, toComplete)
+	}
+
+	if cmd.passthrough && len(cmdArgs) > 0 {
+		return completeTool(pkgRoot, cmdArgs[0], append(cmdArgs[1:], toComplete))
+	}
+
+	if strings.HasPrefix(toComplete, "-") && !afterSeparator {
+		return getFlagCompletion(cmdFlags, toComplete)
+	}
+
+	candidates := []string{}
+	switch cmd.name {
+	case "exec", "remove", "upgrade":
+		candidates = getToolNames(pkgRoot)
+	case "env", "completion":
+		if len(cmdArgs) == 0 {
+			candidates = gomodrun.Shells
+		}
+	case "add", "shims", "batch":
+		return &gomodrun.Completion{Directive: gomodrun.CompDirectiveDefault}
+	}
+
+	return &gomodrun.Completion{
+		Candidates: filterCandidates(candidates, toComplete),
+		Directive:  gomodrun.CompDirectiveNoFileComp,
+	}
+}
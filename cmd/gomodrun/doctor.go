@@ -0,0 +1,56 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/dustinblackman/gomodrun"
+	"github.com/fatih/color"
+)
+
+func runDoctor(flags *flag.FlagSet) func(pkgRoot string, args []string) {
+	asJSON := flags.Bool("json", false, "Print the checks as JSON.")
+
+	return func(pkgRoot string, args []string) {
+		if len(args) > 0 {
+			exitWithError(errors.New("doctor does not take any arguments"))
+		}
+
+		checks := gomodrun.Doctor(pkgRoot)
+
+		failed := false
+		for _, check := range checks {
+			if check.Status == gomodrun.CheckFail {
+				failed = true
+			}
+		}
+
+		if *asJSON {
+			output, err := json.MarshalIndent(checks, "", "  ")
+			if err != nil {
+				exitWithError(err)
+			}
+			fmt.Println(string(output))
+		} else {
+			statusColors := map[string]func(format string, a ...interface{}) string{
+				gomodrun.CheckPass: color.GreenString,
+				gomodrun.CheckWarn: color.YellowString,
+				gomodrun.CheckFail: color.RedString,
+			}
+
+			for _, check := range checks {
+				fmt.Printf("%s %s: %s\n", statusColors[check.Status]("[%s]", check.Status), check.Name, check.Message)
+				if check.Hint != "" {
+					fmt.Printf("       %s\n", check.Hint)
+				}
+			}
+		}
+
+		if failed {
+			os.Exit(1)
+		}
+	}
+}
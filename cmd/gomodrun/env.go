@@ -0,0 +1,49 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+
+	"github.com/dustinblackman/gomodrun"
+)
+
+func getDefaultShell() string {
+	if shell := os.Getenv("SHELL"); shell != "" {
+		return filepath.Base(shell)
+	}
+
+	if runtime.GOOS == "windows" {
+		return "powershell"
+	}
+
+	return "bash"
+}
+
+func runEnv(flags *flag.FlagSet) func(pkgRoot string, args []string) {
+	return func(pkgRoot string, args []string) {
+		if len(args) > 1 {
+			exitWithError(errors.New("env takes at most one shell"))
+		}
+
+		shell := getDefaultShell()
+		if len(args) > 0 {
+			shell = args[0]
+		}
+
+		gomodrunPath, err := os.Executable()
+		if err != nil {
+			exitWithError(err)
+		}
+
+		env, err := gomodrun.Env(pkgRoot, shell, &gomodrun.ShimOptions{GomodrunPath: gomodrunPath})
+		if err != nil {
+			exitWithError(err)
+		}
+
+		fmt.Print(env)
+	}
+}
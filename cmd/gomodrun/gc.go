@@ -0,0 +1,74 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dustinblackman/gomodrun"
+)
+
+func parseDuration(value string) (time.Duration, error) {
+	if strings.HasSuffix(value, "d") {
+		days, err := strconv.Atoi(strings.TrimSuffix(value, "d"))
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %s", value)
+		}
+		return time.Duration(days) * 24 * time.Hour, nil
+	}
+
+	return time.ParseDuration(value)
+}
+
+func parseBytes(value string) (int64, error) {
+	units := []string{"TB", "GB", "MB", "KB", "B"}
+	multiplier := int64(1) << 40
+	for _, unit := range units {
+		if strings.HasSuffix(strings.ToUpper(value), unit) {
+			size, err := strconv.ParseFloat(strings.TrimSpace(value[:len(value)-len(unit)]), 64)
+			if err != nil {
+				return 0, fmt.Errorf("invalid size %s", value)
+			}
+			return int64(size * float64(multiplier)), nil
+		}
+		multiplier /= 1024
+	}
+
+	return strconv.ParseInt(value, 10, 64)
+}
+
+func runGC(flags *flag.FlagSet) func(pkgRoot string, args []string) {
+	options := &gomodrun.GCOptions{}
+
+	flags.Func("max-age", "Remove binaries not used within the `duration`, such as 30d or 12h.", func(value string) (err error) {
+		options.MaxAge, err = parseDuration(value)
+		return err
+	})
+	flags.IntVar(&options.MaxVersions, "max-versions", 0, "Keep at most this many builds per tool, most recently used first.")
+	flags.Func("max-size", "Evict least recently used binaries until the cache fits the `size`, such as 5GB or 500MB.", func(value string) (err error) {
+		options.MaxSize, err = parseBytes(value)
+		return err
+	})
+	flags.StringVar(&options.CacheDir, "cache-dir", "", "Cache directory to collect, such as a shared cache. Defaults to .gomodrun in the package root.")
+	flags.BoolVar(&options.DryRun, "dry-run", false, "List what would be removed and the space reclaimed without deleting anything.")
+
+	return func(pkgRoot string, args []string) {
+		if len(args) > 0 {
+			exitWithError(errors.New("gc does not take any arguments"))
+		}
+
+		if options.MaxAge == 0 && options.MaxVersions == 0 && options.MaxSize == 0 {
+			exitWithError(errors.New("gc requires at least one of --max-age, --max-versions or --max-size"))
+		}
+
+		report, err := gomodrun.GC(pkgRoot, options)
+		if err != nil {
+			exitWithError(err)
+		}
+
+		printReport(report)
+	}
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"errors"
+	"flag"
+
+	"github.com/dustinblackman/gomodrun"
+)
+
+func runInit(flags *flag.FlagSet) func(pkgRoot string, args []string) {
+	options := &gomodrun.InitOptions{}
+	flags.StringVar(&options.Preset, "preset", "", "Adds a named set of tools, one of lint, test, mock, proto or release.")
+
+	return func(pkgRoot string, args []string) {
+		if len(args) > 0 {
+			exitWithError(errors.New("init does not take any arguments"))
+		}
+
+		changed, err := gomodrun.Init(pkgRoot, options)
+		if err != nil {
+			exitWithError(err)
+		}
+
+		for _, filePath := range changed {
+			printf("wrote %s\n", filePath)
+		}
+	}
+}
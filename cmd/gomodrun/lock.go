@@ -0,0 +1,53 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/dustinblackman/gomodrun"
+)
+
+func runLock(flags *flag.FlagSet) func(pkgRoot string, args []string) {
+	return func(pkgRoot string, args []string) {
+		if len(args) > 0 {
+			exitWithError(errors.New("lock does not take any arguments"))
+		}
+
+		lock, err := gomodrun.Lock(pkgRoot)
+		if err != nil {
+			exitWithError(err)
+		}
+
+		for _, tool := range lock.Tools {
+			printf("locked %s %s %s\n", tool.Name, tool.Version, tool.BinaryHash)
+		}
+	}
+}
+
+func runVerify(flags *flag.FlagSet) func(pkgRoot string, args []string) {
+	options := &gomodrun.VerifyOptions{}
+	flags.BoolVar(&options.Rebuild, "locked", false, "Rebuild every tool in a temporary cache instead of trusting cached binaries.")
+
+	return func(pkgRoot string, args []string) {
+		if len(args) > 0 {
+			exitWithError(errors.New("verify does not take any arguments"))
+		}
+
+		mismatches, err := gomodrun.Verify(pkgRoot, options)
+		if err != nil {
+			exitWithError(err)
+		}
+
+		if len(mismatches) == 0 {
+			printf("tools match %s\n", gomodrun.LockFileName)
+			return
+		}
+
+		for idx := range mismatches {
+			fmt.Fprintf(os.Stderr, "mismatch: %s\n", mismatches[idx].String())
+		}
+		exitWithError(fmt.Errorf("tools no longer build as recorded in %s, run gomodrun lock to update it", gomodrun.LockFileName))
+	}
+}
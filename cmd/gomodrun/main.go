@@ -3,11 +3,15 @@ package main
 
 import (
 	"errors"
+	"flag"
 	"fmt"
+	"log"
 	"os"
+	"path/filepath"
 	"strings"
 
 	"github.com/fatih/color"
+	"github.com/mattn/go-isatty"
 
 	"github.com/dustinblackman/gomodrun"
 )
@@ -18,77 +22,384 @@ var (
 	date    = "unknown"
 )
 
+var (
+	// logger logs each phase of running a tool when --verbose is set.
+	logger gomodrun.Logger
+	// quiet hides gomodrun's own status messages when --quiet is set.
+	quiet bool
+	// buildOutput streams build output to stderr when --build-output is set.
+	buildOutput bool
+	// overrides are the sources tools are built from instead of go.mod, set with --override.
+	overrides = map[string]string{}
+)
+
+// printf prints a status message unless --quiet is set.
+func printf(format string, args ...interface{}) {
+	if !quiet {
+		fmt.Printf(format, args...)
+	}
+}
+
 func exitWithError(err error) {
 	color.Red("gomodrun: " + err.Error())
 	os.Exit(1)
 }
 
-func main() {
-	if len(os.Args) <= 1 {
-		exitWithError(errors.New("no binary name provided"))
+func formatBytes(size int64) string {
+	units := []string{"B", "KB", "MB", "GB", "TB"}
+	value := float64(size)
+	unit := 0
+	for value >= 1024 && unit < len(units)-1 {
+		value /= 1024
+		unit++
+	}
+
+	if unit == 0 {
+		return fmt.Sprintf("%d %s", size, units[unit])
+	}
+
+	return fmt.Sprintf("%.1f %s", value, units[unit])
+}
+
+func printReport(report *gomodrun.TidyReport) {
+	action := "removed"
+	if report.DryRun {
+		action = "would remove"
+	}
+
+	for _, entry := range report.Removed {
+		relPath, err := filepath.Rel(report.PkgRoot, entry.Path)
+		if err != nil {
+			relPath = entry.Path
+		}
+		printf("%s %s (%s): %s\n", action, relPath, formatBytes(entry.Size), entry.Reason)
+	}
+
+	if report.DryRun {
+		printf("would reclaim %s\n", formatBytes(report.ReclaimedBytes()))
+	} else {
+		printf("reclaimed %s\n", formatBytes(report.ReclaimedBytes()))
+	}
+}
+
+func getCommands() []*command {
+	return []*command{
+		{
+			name:        "exec",
+			usage:       "cli-name [parameters]",
+			description: "Builds every tool in the tools file and runs cli-name with all of them on PATH.",
+			passthrough: true,
+			setup:       runExec,
+		},
+		{
+			name:        "batch",
+			usage:       "[--parallel count] [file]",
+			description: "Runs many tools from file or stdin, one `[-C dir] cli-name [parameters]` per line, building each once and printing each tool's output in order. Exits with the first failure's exit code.",
+			setup:       runBatch,
+		},
+		{
+			name:        "tidy",
+			usage:       "[tidy flags]",
+			description: "Cleans .gomodrun of any outdated binaries.",
+			setup:       runTidy,
+		},
+		{
+			name:        "gc",
+			usage:       "[gc flags]",
+			description: "Evicts binaries from the cache using age, version and size policies.",
+			setup:       runGC,
+		},
+		{
+			name:        "env",
+			usage:       "[bash|zsh|sh|fish|powershell]",
+			description: "Prints shell commands that put shims for every tool in the tools file first on PATH. Defaults to $SHELL, other POSIX shells such as dash get sh commands.",
+			setup:       runEnv,
+		},
+		{
+			name:        "shims",
+			usage:       "[--check] [dir]",
+			description: "Writes POSIX shell and .cmd shims for every tool in the tools file to dir, defaulting to bin in the package root. --check fails when shims are out of sync.",
+			setup:       runShims,
+		},
+		{
+			name:        "init",
+			usage:       "[--preset lint|test|mock|proto|release]",
+			description: "Creates a tools file, adds .gomodrun to .gitignore and writes a starter .gomodrun.yml. --preset adds a named set of tools.",
+			setup:       runInit,
+		},
+		{
+			name:        "add",
+			usage:       "[--build] package[@version]...",
+			description: "Adds packages to the tools file, creating it if missing, and requires their modules in go.mod. --build prebuilds the binaries.",
+			setup:       runAdd,
+		},
+		{
+			name:        "remove",
+			usage:       "cli-name|package...",
+			description: "Removes tools from the tools file, drops unused requires from go.mod and deletes their cached binaries.",
+			setup:       runRemove,
+		},
+		{
+			name:        "outdated",
+			usage:       "[--prerelease]",
+			description: "Lists each tool's version in go.mod against the latest versions available from GOPROXY.",
+			setup:       runOutdated,
+		},
+		{
+			name:        "upgrade",
+			usage:       "[--major] [--prerelease] --all|cli-name...",
+			description: "Bumps tools in go.mod to their latest version with the same major version. --major moves to the latest major version, updating the tools file.",
+			setup:       runUpgrade,
+		},
+		{
+			name:        "lock",
+			usage:       "",
+			description: "Builds every tool in the tools file and records each module version, go.sum hash, toolchain, build settings, dependencies and binary hash in gomodrun.lock.",
+			setup:       runLock,
+		},
+		{
+			name:        "verify",
+			usage:       "[--locked]",
+			description: "Fails when any tool no longer builds as recorded in gomodrun.lock. --locked rebuilds every tool in a temporary cache instead of trusting cached binaries.",
+			setup:       runVerify,
+		},
+		{
+			name:        "doctor",
+			usage:       "[--json]",
+			description: "Checks the go installation, go env, tools file and .gomodrun cache, printing hints for anything failing. --json prints the checks as JSON.",
+			setup:       runDoctor,
+		},
+		{
+			name:        "completion",
+			usage:       "[bash|zsh|fish|powershell]",
+			description: "Prints a completion script for gomodrun's flags, commands and tools. Defaults to $SHELL. Tools built with Cobra complete their own arguments.",
+			setup:       runCompletion,
+		},
+		{
+			name:        "__complete",
+			usage:       "[args...] word",
+			description: "Prints completions for word in the Cobra __complete format, used by the completion scripts.",
+			hidden:      true,
+			noFlags:     true,
+			setup:       runComplete,
+		},
 	}
+}
+
+func getCommand(name string) *command {
+	for _, cmd := range getCommands() {
+		if cmd.name == name {
+			return cmd
+		}
+	}
+
+	return nil
+}
 
-	if os.Args[1] == "--help" || os.Args[1] == "--version" {
-		fmt.Printf(`gomodrun %s
+func printVersion() {
+	fmt.Printf(`gomodrun %s
 Build Date: %s
 https://github.com/dustinblackman/gomodrun/commit/%s
+`, version, date, commit)
+}
 
+func printHelp(flags *flag.FlagSet) {
+	printVersion()
+	fmt.Print(`
 The forgotten go tool that executes and caches binaries included in go.mod files.
 
 Usage:
-	gomodrun [flags] cli-name [parameters]
+	gomodrun [flags] [--] cli-name [parameters]
+`)
+
+	for _, cmd := range getCommands() {
+		if !cmd.hidden {
+			fmt.Printf("\tgomodrun [flags] %s %s\n", cmd.name, cmd.usage)
+		}
+	}
+
+	fmt.Print(`	gomodrun help [command]
 
 Example:
 	gomodrun golangci-lint run
 	echo example.json | gomodrun gojson > example.go
 	gomodrun -r ./alternative-tools-dir golangci-lint run
+	gomodrun -v golangci-lint run
+	gomodrun golang.org/x/tools/cmd/deadcode@v0.20.0 ./...
+	gomodrun --override golangci-lint=../golangci-lint golangci-lint run
+	gomodrun --pkg-root=./alternative-tools-dir -- init --help
+	gomodrun tidy --dry-run
+	gomodrun gc --max-age 30d --max-versions 2 --max-size 5GB
+	gomodrun exec goreleaser release
+	gomodrun batch --parallel 4 generate.txt
+	eval "$(gomodrun env bash)"
+	gomodrun shims --check ./bin
+	gomodrun init --preset lint
+	gomodrun add --build github.com/golang/mock/mockgen@v1.6.0
+	gomodrun remove mockgen
+	gomodrun upgrade golangci-lint
+	gomodrun lock
+	gomodrun verify --locked
+	gomodrun doctor --json
+	source <(gomodrun completion bash)
 
 Flags:
-  -r, --pkg-root string  Specify alternative root directory containing a go.mod and tools file. Defaults to walking up the file tree to locate go.mod.
-  -t, --tidy  Cleans .gomodrun of any outdated binaries.`, version, date, commit)
-		os.Exit(0)
+`)
+	printFlags(flags)
+
+	fmt.Print("\nCommands:\n")
+	for _, cmd := range getCommands() {
+		if !cmd.hidden {
+			fmt.Printf("  %s  %s\n", cmd.name, cmd.description)
+		}
+	}
+
+	fmt.Print(`
+cli-name can also be module/pkg@version to run a tool that isn't in the tools file without changing go.mod.
+Flags must come before cli-name, everything after it is passed to the tool. Use -- to run a tool that shares a
+name with a command. Run gomodrun help [command] for a command's flags.
+`)
+}
+
+func getRootCommand() *command {
+	return &command{
+		passthrough: true,
+		setup:       runRoot,
 	}
+}
 
-	cmdPosition := 1
-	argsPosition := 2
-	pkgRoot := ""
+func runRoot(flags *flag.FlagSet) func(pkgRoot string, args []string) {
+	pkgRoot := flags.String("pkg-root", "", "Specify alternative root directory containing a go.mod and tools file. Defaults to walking up the file tree to locate go.mod.")
+	showVersion := flags.Bool("version", false, "Prints the version of gomodrun.")
+	verbose := flags.Bool("verbose", false, "Logs each phase of finding, building and running a tool with how long it took to stderr.")
+	flags.BoolVar(&buildOutput, "build-output", false, "Streams go mod download and go build output to stderr while tools build.")
+	flags.Func("override", "Builds a tool from a local module directory or commit instead of go.mod in its own cache slot, such as `tool=../dir` or tool=@commit. Can be repeated. Also set with "+gomodrun.OverrideEnvPrefix+"<tool>.", func(value string) error {
+		tool, source, ok := strings.Cut(value, "=")
+		if !ok || tool == "" || source == "" {
+			return fmt.Errorf("invalid override %s, expected tool=dir or tool=@commit", value)
+		}
+		overrides[tool] = source
+		return nil
+	})
+	flags.BoolVar(&quiet, "quiet", false, "Hides gomodrun's own status messages. Errors and the tool's output are still printed.")
+	tidy := flags.Bool("tidy", false, "Cleans .gomodrun of any outdated binaries. Same as the tidy command.")
+	tidyOptions := addTidyFlags(flags, "Used with --tidy. ")
 
-	skipNext := false
-	for idx, entry := range os.Args {
-		if skipNext || idx == 0 {
-			skipNext = false
-			continue
+	return func(_ string, args []string) {
+		if *showVersion {
+			printVersion()
+			os.Exit(0)
 		}
 
-		if entry == "-t" || entry == "--tidy" {
-			err := gomodrun.Tidy(pkgRoot)
-			if err != nil {
-				exitWithError(err)
+		if *verbose && quiet {
+			exitWithError(errors.New("--verbose and --quiet can not be used together"))
+		}
+
+		if *verbose {
+			logger = log.New(os.Stderr, "gomodrun: ", 0)
+		}
+
+		cmdName, args := splitRootArgs(args)
+
+		tidyFlagSet := false
+		flags.Visit(func(f *flag.Flag) {
+			if f.Name == "dry-run" || f.Name == "keep-go-version" || f.Name == "keep-recent-go" {
+				tidyFlagSet = true
 			}
+		})
+
+		if *tidy {
+			if len(args) > 0 {
+				exitWithError(fmt.Errorf("--tidy can not be used with %s", args[0]))
+			}
+			tidyWithOptions(*pkgRoot, tidyOptions)
 			os.Exit(0)
 		}
 
-		if entry == "-r" || entry == "--pkg-root" {
-			pkgRoot = os.Args[idx+1]
-			skipNext = true
-			continue
+		if tidyFlagSet {
+			exitWithError(errors.New("--dry-run, --keep-go-version and --keep-recent-go can only be used with --tidy"))
 		}
 
-		if !strings.HasPrefix(entry, "-") {
-			cmdPosition = idx
-			argsPosition = idx + 1
-			break
+		if len(args) == 0 {
+			exitWithError(errors.New("no binary name provided"))
 		}
+
+		if cmdName == "help" {
+			if len(args) == 1 {
+				printHelp(flags)
+				os.Exit(0)
+			}
+
+			cmd := getCommand(args[1])
+			if cmd == nil {
+				exitWithError(fmt.Errorf("unknown command %s", args[1]))
+			}
+			cmd.run(*pkgRoot, []string{"--help"})
+			os.Exit(0)
+		}
+
+		if cmdName != "" {
+			getCommand(cmdName).run(*pkgRoot, args[1:])
+			os.Exit(0)
+		}
+
+		runTool(gomodrun.Run, *pkgRoot, args)
 	}
+}
 
-	exitCode, err := gomodrun.Run(os.Args[cmdPosition], os.Args[argsPosition:], &gomodrun.Options{
+// splitRootArgs returns the command named by the first argument, or an empty string when it's a tool, along with the
+// arguments without a leading `--`. Commands shadow tools with the same name, which can still be run after `--`.
+func splitRootArgs(args []string) (string, []string) {
+	if len(args) > 0 && args[0] == "--" {
+		return "", args[1:]
+	}
+
+	if len(args) > 0 && (args[0] == "help" || getCommand(args[0]) != nil) {
+		return args[0], args
+	}
+
+	return "", args
+}
+
+func main() {
+	getRootCommand().run("", os.Args[1:])
+}
+
+func runExec(flags *flag.FlagSet) func(pkgRoot string, args []string) {
+	return func(pkgRoot string, args []string) {
+		if len(args) > 0 && args[0] == "--" {
+			args = args[1:]
+		}
+
+		if len(args) == 0 {
+			exitWithError(errors.New("no binary name provided to exec"))
+		}
+
+		runTool(gomodrun.Exec, pkgRoot, args)
+	}
+}
+
+func runTool(run func(binName string, args []string, options *gomodrun.Options) (int, error), pkgRoot string, args []string) {
+	options := &gomodrun.Options{
 		Stdin:   os.Stdin,
 		Stdout:  os.Stdout,
 		Stderr:  os.Stderr,
 		Env:     os.Environ(),
 		PkgRoot: pkgRoot,
-	})
+		Logger:  logger,
+
+		Overrides: overrides,
+	}
+
+	if !quiet && (isatty.IsTerminal(os.Stderr.Fd()) || isatty.IsCygwinTerminal(os.Stderr.Fd())) {
+		options.Progress = os.Stderr
+	}
+
+	if buildOutput {
+		options.BuildOutput = os.Stderr
+	}
 
+	exitCode, err := run(args[0], args[1:], options)
 	if err != nil {
 		exitWithError(err)
 	}
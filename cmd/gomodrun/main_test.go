@@ -0,0 +1,53 @@
+package main
+
+import (
+	. "github.com/onsi/ginkgo"
+	"github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("main", func() {
+	AfterEach(func() {
+		quiet = false
+		buildOutput = false
+		overrides = map[string]string{}
+	})
+
+	Context("runRoot", func() {
+		type parsed struct {
+			pkgRoot string
+			cmdName string
+			args    []string
+		}
+
+		table.DescribeTable("should find the command or tool to run",
+			func(args []string, expected parsed) {
+				root := getRootCommand()
+				flags, _ := root.setupFlags()
+				cmdName, remaining := splitRootArgs(root.parse(flags, args))
+
+				Expect(parsed{
+					pkgRoot: flags.Lookup("pkg-root").Value.String(),
+					cmdName: cmdName,
+					args:    remaining,
+				}).To(Equal(expected))
+			},
+			table.Entry("a tool", []string{"golangci-lint", "run"},
+				parsed{args: []string{"golangci-lint", "run"}}),
+			table.Entry("-r before a tool", []string{"-r", "./tools", "golangci-lint", "run", "--fix"},
+				parsed{pkgRoot: "./tools", args: []string{"golangci-lint", "run", "--fix"}}),
+			table.Entry("--pkg-root=value before a command", []string{"--pkg-root=./tools", "init", "--preset", "lint"},
+				parsed{pkgRoot: "./tools", cmdName: "init", args: []string{"init", "--preset", "lint"}}),
+			table.Entry("the tool's own flags", []string{"golangci-lint", "-r", "./other", "--", "run"},
+				parsed{args: []string{"golangci-lint", "-r", "./other", "--", "run"}}),
+			table.Entry("-- running a tool named like a command", []string{"-q", "--", "init", "--help"},
+				parsed{args: []string{"init", "--help"}}),
+			table.Entry("-- as a flag value", []string{"-r", "--", "init"},
+				parsed{pkgRoot: "--", cmdName: "init", args: []string{"init"}}),
+			table.Entry("help", []string{"help", "gc"},
+				parsed{cmdName: "help", args: []string{"help", "gc"}}),
+			table.Entry("nothing", []string{"-q"},
+				parsed{args: []string{}}),
+		)
+	})
+})
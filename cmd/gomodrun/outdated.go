@@ -0,0 +1,68 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/dustinblackman/gomodrun"
+)
+
+func runOutdated(flags *flag.FlagSet) func(pkgRoot string, args []string) {
+	options := &gomodrun.VersionOptions{}
+	flags.BoolVar(&options.Prerelease, "prerelease", false, "Include prerelease versions when finding the latest version.")
+
+	return func(pkgRoot string, args []string) {
+		if len(args) > 0 {
+			exitWithError(errors.New("outdated does not take any arguments"))
+		}
+
+		toolVersions, err := gomodrun.Outdated(pkgRoot, options)
+		if err != nil {
+			exitWithError(err)
+		}
+
+		writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(writer, "TOOL\tMODULE\tCURRENT\tSAME MAJOR\tLATEST")
+		for _, toolVersion := range toolVersions {
+			latest := toolVersion.Latest
+			if toolVersion.OutdatedMajor() {
+				latest = toolVersion.LatestModule + "@" + latest
+			}
+			fmt.Fprintf(writer, "%s\t%s\t%s\t%s\t%s\n", toolVersion.ImportPath, toolVersion.Module, toolVersion.Version, toolVersion.LatestSameMajor, latest)
+		}
+		writer.Flush() //nolint // Ignore error, nothing to do if stdout fails.
+	}
+}
+
+func runUpgrade(flags *flag.FlagSet) func(pkgRoot string, args []string) {
+	options := &gomodrun.VersionOptions{}
+	all := flags.Bool("all", false, "Upgrade every tool in the tools file.")
+	flags.BoolVar(&options.Major, "major", false, "Move to the latest major version, updating the tools file imports.")
+	flags.BoolVar(&options.Prerelease, "prerelease", false, "Include prerelease versions when finding the latest version.")
+
+	return func(pkgRoot string, args []string) {
+		if *all == (len(args) > 0) {
+			exitWithError(errors.New("upgrade requires either tool names or --all"))
+		}
+
+		upgraded, err := gomodrun.Upgrade(pkgRoot, args, options)
+		if err != nil {
+			exitWithError(err)
+		}
+
+		if len(upgraded) == 0 {
+			printf("all tools are up to date\n")
+		}
+
+		for _, toolVersion := range upgraded {
+			if options.Major && toolVersion.OutdatedMajor() {
+				printf("upgraded %s %s => %s %s\n", toolVersion.Module, toolVersion.Version, toolVersion.LatestModule, toolVersion.Latest)
+			} else {
+				printf("upgraded %s %s => %s\n", toolVersion.Module, toolVersion.Version, toolVersion.LatestSameMajor)
+			}
+		}
+	}
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"errors"
+	"flag"
+
+	"github.com/dustinblackman/gomodrun"
+)
+
+func runRemove(flags *flag.FlagSet) func(pkgRoot string, args []string) {
+	return func(pkgRoot string, args []string) {
+		if len(args) == 0 {
+			exitWithError(errors.New("no tool provided to remove"))
+		}
+
+		for _, tool := range args {
+			change, err := gomodrun.Remove(pkgRoot, tool)
+			if err != nil {
+				exitWithError(err)
+			}
+
+			printf("removed %s from %s\n", change.ImportPath, change.ToolsFile)
+			if change.ModuleRemoved {
+				printf("removed %s %s from go.mod\n", change.Module, change.Version)
+			}
+			for _, cachedBin := range change.CachedBins {
+				printf("deleted %s\n", cachedBin)
+			}
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path"
+
+	"github.com/dustinblackman/gomodrun"
+)
+
+func runShims(flags *flag.FlagSet) func(pkgRoot string, args []string) {
+	check := flags.Bool("check", false, "Fail when the shims in dir are out of sync with the tools file instead of writing them.")
+
+	return func(pkgRoot string, args []string) {
+		if len(args) > 1 {
+			exitWithError(errors.New("shims takes at most one dir"))
+		}
+
+		shimDir := ""
+		if len(args) > 0 {
+			shimDir = args[0]
+		}
+
+		if shimDir == "" {
+			var err error
+			if pkgRoot == "" {
+				pkgRoot, err = gomodrun.GetPkgRoot()
+				if err != nil {
+					exitWithError(err)
+				}
+			}
+			shimDir = path.Join(pkgRoot, "bin")
+		}
+
+		if *check {
+			outOfSync, err := gomodrun.CheckShims(pkgRoot, shimDir, &gomodrun.ShimOptions{})
+			if err != nil {
+				exitWithError(err)
+			}
+
+			if len(outOfSync) == 0 {
+				return
+			}
+
+			for _, shimName := range outOfSync {
+				fmt.Fprintf(os.Stderr, "out of sync: %s\n", path.Join(shimDir, shimName))
+			}
+			exitWithError(fmt.Errorf("shims in %s are out of sync with the tools file, run gomodrun shims to update them", shimDir))
+		}
+
+		shimPaths, err := gomodrun.WriteShims(pkgRoot, shimDir, &gomodrun.ShimOptions{})
+		if err != nil {
+			exitWithError(err)
+		}
+
+		for _, shimPath := range shimPaths {
+			printf("wrote %s\n", shimPath)
+		}
+	}
+}
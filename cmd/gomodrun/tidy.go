@@ -0,0 +1,51 @@
+package main
+
+import (
+	"errors"
+	"flag"
+
+	"github.com/dustinblackman/gomodrun"
+)
+
+// addTidyFlags adds the tidy flags to flags, prefixing each flag's usage.
+func addTidyFlags(flags *flag.FlagSet, usagePrefix string) *gomodrun.TidyOptions {
+	options := &gomodrun.TidyOptions{}
+	flags.BoolVar(&options.DryRun, "dry-run", false, usagePrefix+"Lists disk usage per go version, what would be removed and the space reclaimed without deleting anything.")
+	flags.Var((*stringsFlag)(&options.KeepGoVersions), "keep-go-version", usagePrefix+"Keeps binaries built with this go `version`. Can be repeated or comma separated.")
+	flags.IntVar(&options.KeepRecent, "keep-recent-go", 0, usagePrefix+"Keeps binaries built with this `count` of the newest go versions.")
+
+	return options
+}
+
+func tidyWithOptions(pkgRoot string, options *gomodrun.TidyOptions) {
+	report, err := gomodrun.TidyWithReport(pkgRoot, options)
+	if err != nil {
+		exitWithError(err)
+	}
+
+	if !options.DryRun {
+		return
+	}
+
+	for _, usage := range report.GoVersions {
+		status := "remove"
+		if usage.Kept {
+			status = "keep"
+		}
+		printf("%s: %s (%s)\n", usage.Version, formatBytes(usage.Size), status)
+	}
+
+	printReport(report)
+}
+
+func runTidy(flags *flag.FlagSet) func(pkgRoot string, args []string) {
+	options := addTidyFlags(flags, "")
+
+	return func(pkgRoot string, args []string) {
+		if len(args) > 0 {
+			exitWithError(errors.New("tidy does not take any arguments"))
+		}
+
+		tidyWithOptions(pkgRoot, options)
+	}
+}
@@ -0,0 +1,286 @@
+// Package gomodrun is the forgotten go tool that executes and caches binaries included in go.mod files.
+// This makes it easy to version cli tools in your projects such as `golangci-lint`
+// and `ginkgo` that are versioned locked to what you specify in `go.mod`.
+// Binaries are cached by go version and package version.
+package gomodrun
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// Completion directives, matching Cobra's ShellCompDirective so a tool's own completions can be passed through.
+const (
+	CompDirectiveDefault       = 0
+	CompDirectiveError         = 1
+	CompDirectiveNoSpace       = 2
+	CompDirectiveNoFileComp    = 4
+	CompDirectiveFilterFileExt = 8
+	CompDirectiveFilterDirs    = 16
+)
+
+// Completion is the result of a Cobra style `__complete` request.
+type Completion struct {
+	Candidates []string // Each candidate may be followed by a tab and a description.
+	Directive  int
+}
+
+// String formats the completion in the Cobra `__complete` output format read by the completion scripts.
+func (c *Completion) String() string {
+	output := ""
+	for _, candidate := range c.Candidates {
+		output += candidate + "\n"
+	}
+
+	return output + ":" + strconv.Itoa(c.Directive) + "\n"
+}
+
+// parseCompletion parses Cobra `__complete` output, returning nil if it doesn't end in a directive.
+func parseCompletion(output string) *Completion {
+	lines := strings.Split(strings.TrimRight(output, "\n"), "\n")
+	last := lines[len(lines)-1]
+	if !strings.HasPrefix(last, ":") {
+		return nil
+	}
+
+	directive, err := strconv.Atoi(strings.TrimPrefix(last, ":"))
+	if err != nil {
+		return nil
+	}
+
+	candidates := []string{}
+	for _, line := range lines[:len(lines)-1] {
+		if line != "" {
+			candidates = append(candidates, line)
+		}
+	}
+
+	return &Completion{
+		Candidates: candidates,
+		Directive:  directive,
+	}
+}
+
+// hasCobraCompletion returns true if the cached binary includes Cobra's completion command. The answer is kept in a
+// marker next to the binary, which is removed when the binary is rebuilt, so the binary is only read once.
+func hasCobraCompletion(binPath string) (bool, error) {
+	markerPath := getCompletionMarkerPath(binPath)
+	if marker, err := ioutil.ReadFile(markerPath); err == nil {
+		return strings.TrimSpace(string(marker)) == "true", nil
+	}
+
+	binary, err := ioutil.ReadFile(binPath)
+	if err != nil {
+		return false, err
+	}
+
+	supported := bytes.Contains(binary, []byte("__complete"))
+	ioutil.WriteFile(markerPath, []byte(strconv.FormatBool(supported)+"\n"), 0o644) //nolint // Ignore error, the binary is read again next time.
+
+	return supported, nil
+}
+
+// CompleteTool returns completions for a tool's arguments using its Cobra style `__complete` command. The last
+// argument is the word being completed. Nil is returned if the tool isn't cached yet, as building or downloading would
+// stall the shell, or if it doesn't support Cobra completions.
+func CompleteTool(binName string, args []string, options *Options) (*Completion, error) {
+	runner, err := NewRunner(options)
+	if err != nil {
+		return nil, err
+	}
+
+	resolution, err := runner.resolveLocal(binName)
+	if err != nil || resolution == nil {
+		return nil, err
+	}
+
+	cachedBin := resolution.CachePath
+	if !runner.cache().Exists(cachedBin) {
+		return nil, nil
+	}
+
+	// Only run tools that include Cobra's completion command, anything else could treat the arguments as input.
+	supported, err := hasCobraCompletion(cachedBin)
+	if err != nil {
+		return nil, err
+	}
+	if !supported {
+		return nil, nil
+	}
+
+	runner.cache().MarkUsed(cachedBin)
+
+	env, err := runner.getToolEnv(binName)
+	if err != nil {
+		return nil, err
+	}
+
+	cmd := exec.Command(cachedBin, append([]string{"__complete"}, args...)...)
+	cmd.Env = env
+	cmd.Dir = runner.Dir
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, nil
+	}
+
+	return parseCompletion(string(output)), nil
+}
+
+const bashCompletion = `# bash completion for gomodrun
+_gomodrun() {
+    local cur="${COMP_WORDS[COMP_CWORD]}"
+    local out directive line
+    out=$(gomodrun __complete "${COMP_WORDS[@]:1:COMP_CWORD}" 2>/dev/null)
+    directive=${out##*:}
+    out=${out%:*}
+
+    if (( directive & 1 )); then
+        return
+    fi
+
+    if (( directive & 16 )); then
+        COMPREPLY=($(compgen -d -- "$cur"))
+        return
+    fi
+
+    COMPREPLY=()
+    while IFS='' read -r line; do
+        line=${line%%$'\t'*}
+        if [[ -n $line && $line == "$cur"* ]]; then
+            COMPREPLY+=("$line")
+        fi
+    done <<< "$out"
+
+    if (( ${#COMPREPLY[@]} == 0 && !(directive & 4) )); then
+        COMPREPLY=($(compgen -f -- "$cur"))
+    fi
+
+    if (( directive & 2 )); then
+        compopt -o nospace
+    fi
+}
+
+complete -F _gomodrun gomodrun
+`
+
+const zshCompletion = `#compdef gomodrun
+# zsh completion for gomodrun
+_gomodrun() {
+    local out directive line name desc
+    local -a candidates
+    out=$(gomodrun __complete "${(@)words[2,CURRENT]}" 2>/dev/null)
+    directive=${out##*:}
+    out=${out%:*}
+
+    if (( directive & 1 )); then
+        return 1
+    fi
+
+    if (( directive & 16 )); then
+        _path_files -/
+        return
+    fi
+
+    for line in "${(@f)out}"; do
+        [[ -z $line ]] && continue
+        name=${line%%$'\t'*}
+        desc=""
+        [[ $line == *$'\t'* ]] && desc=${line#*$'\t'}
+        candidates+=("${name//:/\\:}${desc:+:$desc}")
+    done
+
+    if (( ${#candidates} == 0 )) && ! (( directive & 4 )); then
+        _files
+        return
+    fi
+
+    if (( directive & 2 )); then
+        _describe 'gomodrun' candidates -S ''
+    else
+        _describe 'gomodrun' candidates
+    fi
+}
+
+compdef _gomodrun gomodrun
+`
+
+const fishCompletion = `# fish completion for gomodrun
+function __gomodrun_complete
+    set -l args (commandline -opc)[2..-1] (commandline -ct)
+    set -l out (gomodrun __complete $args 2>/dev/null)
+    set -l directive (string replace -r '^:' '' -- $out[-1])
+    set -e out[-1]
+
+    if test (math "bitand($directive, 1)") -ne 0
+        return
+    end
+
+    if test (math "bitand($directive, 16)") -ne 0
+        __fish_complete_directories (commandline -ct)
+        return
+    end
+
+    for line in $out
+        echo $line
+    end
+
+    if test (count $out) -eq 0; and test (math "bitand($directive, 4)") -eq 0
+        __fish_complete_path (commandline -ct)
+    end
+end
+
+complete -c gomodrun -f -a '(__gomodrun_complete)'
+`
+
+const powershellCompletion = `# powershell completion for gomodrun
+Register-ArgumentCompleter -Native -CommandName gomodrun -ScriptBlock {
+    param($wordToComplete, $commandAst, $cursorPosition)
+
+    $words = @($commandAst.CommandElements |
+        Where-Object { $_.Extent.StartOffset -lt $cursorPosition } |
+        Select-Object -Skip 1 |
+        ForEach-Object { $_.Extent.Text })
+    if ($wordToComplete -eq '') {
+        $words += '""'
+    }
+
+    $out = @(& gomodrun __complete @words 2>$null)
+    if ($out.Count -eq 0) {
+        return
+    }
+
+    $directive = [int]($out[-1].TrimStart(':'))
+    if ($directive -band 1) {
+        return
+    }
+
+    $out | Select-Object -SkipLast 1 | Where-Object { $_ -ne '' -and $_.Split("` + "`" + `t")[0] -like "$wordToComplete*" } | ForEach-Object {
+        $name, $desc = $_.Split("` + "`" + `t", 2)
+        if (-not $desc) {
+            $desc = $name
+        }
+        [System.Management.Automation.CompletionResult]::new($name, $name, 'ParameterValue', $desc)
+    }
+}
+`
+
+// GetCompletionScript returns the completion script for the given shell. Scripts call `gomodrun __complete` with
+// the words on the command line.
+func GetCompletionScript(shell string) (string, error) {
+	switch shell {
+	case "bash":
+		return bashCompletion, nil
+	case "zsh":
+		return zshCompletion, nil
+	case "fish":
+		return fishCompletion, nil
+	case "powershell", "pwsh":
+		return powershellCompletion, nil
+	}
+
+	return "", fmt.Errorf("unsupported shell %s, expected one of %s", shell, strings.Join(Shells, ", "))
+}
@@ -0,0 +1,144 @@
+package gomodrun
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("completion", func() {
+	Context("GetCompletionScript", func() {
+		It("should return a script calling __complete for each shell", func() {
+			for _, shell := range append(Shells, "pwsh") {
+				script, err := GetCompletionScript(shell)
+				Expect(err).To(BeNil())
+				Expect(script).To(ContainSubstring("gomodrun __complete"))
+			}
+		})
+
+		It("should return an error for unsupported shells", func() {
+			_, err := GetCompletionScript("tcsh")
+			Expect(err).ToNot(BeNil())
+			Expect(err.Error()).To(ContainSubstring("unsupported shell tcsh"))
+		})
+	})
+
+	Context("Completion", func() {
+		It("should round trip through the Cobra format", func() {
+			completion := &Completion{
+				Candidates: []string{"run\tRun the linters", "version"},
+				Directive:  CompDirectiveNoFileComp,
+			}
+			Expect(completion.String()).To(Equal("run\tRun the linters\nversion\n:4\n"))
+			Expect(parseCompletion(completion.String())).To(Equal(completion))
+		})
+
+		It("should not parse output without a directive", func() {
+			Expect(parseCompletion("usage: hello-world\n")).To(BeNil())
+		})
+	})
+
+	Context("CompleteTool", func() {
+		var tempDir string
+		var cachedBin string
+
+		BeforeEach(func() {
+			goVersion, err := getGoVersion()
+			if err != nil {
+				panic(err)
+			}
+
+			tempDir = createProjectFixture()
+			cachedBin = path.Join(tempDir, ".gomodrun", goVersion, "github.com/dustinblackman/go-hello-world-test@v0.0.2/hello-world/hello-world")
+			writeFakeBin(cachedBin)
+		})
+
+		AfterEach(func() {
+			err := os.RemoveAll(tempDir)
+			if err != nil {
+				panic(err)
+			}
+		})
+
+		It("should return nil for tools without Cobra completions", func() {
+			completion, err := CompleteTool("hello-world", []string{""}, &Options{PkgRoot: tempDir})
+			Expect(err).To(BeNil())
+			Expect(completion).To(BeNil())
+		})
+
+		It("should return the completions of tools with Cobra completions", func() {
+			err := ioutil.WriteFile(cachedBin, []byte("#!/bin/sh\n# __complete\nprintf 'run\\tRun the linters\\n%s\\n:4\\n' \"$*\"\n"), 0700)
+			Expect(err).To(BeNil())
+			err = os.Chmod(cachedBin, 0700)
+			Expect(err).To(BeNil())
+
+			completion, err := CompleteTool("hello-world", []string{"--fast", "r"}, &Options{PkgRoot: tempDir})
+			Expect(err).To(BeNil())
+			Expect(completion).To(Equal(&Completion{
+				Candidates: []string{"run\tRun the linters", "__complete --fast r"},
+				Directive:  CompDirectiveNoFileComp,
+			}))
+		})
+
+		It("should not build tools that aren't cached", func() {
+			err := os.Remove(cachedBin)
+			Expect(err).To(BeNil())
+
+			completion, err := CompleteTool("hello-world", []string{""}, &Options{PkgRoot: tempDir})
+			Expect(err).To(BeNil())
+			Expect(completion).To(BeNil())
+			_, err = os.Stat(cachedBin)
+			Expect(os.IsNotExist(err)).To(BeTrue())
+		})
+
+		It("should remember whether tools support Cobra completions", func() {
+			completion, err := CompleteTool("hello-world", []string{""}, &Options{PkgRoot: tempDir})
+			Expect(err).To(BeNil())
+			Expect(completion).To(BeNil())
+
+			marker, err := ioutil.ReadFile(getCompletionMarkerPath(cachedBin))
+			Expect(err).To(BeNil())
+			Expect(string(marker)).To(Equal("false\n"))
+
+			err = ioutil.WriteFile(cachedBin, []byte("#!/bin/sh\n# __complete\nprintf 'run\\n:4\\n'\n"), 0700)
+			Expect(err).To(BeNil())
+			err = os.Chmod(cachedBin, 0700)
+			Expect(err).To(BeNil())
+			completion, err = CompleteTool("hello-world", []string{""}, &Options{PkgRoot: tempDir})
+			Expect(err).To(BeNil())
+			Expect(completion).To(BeNil())
+
+			err = os.Remove(getCompletionMarkerPath(cachedBin))
+			Expect(err).To(BeNil())
+			completion, err = CompleteTool("hello-world", []string{""}, &Options{PkgRoot: tempDir})
+			Expect(err).To(BeNil())
+			Expect(completion).To(Equal(&Completion{Candidates: []string{"run"}, Directive: CompDirectiveNoFileComp}))
+		})
+
+		It("should not download tools that need to be resolved from the network", func() {
+			goProxy := os.Getenv("GOPROXY")
+			err := os.Setenv("GOPROXY", "off")
+			Expect(err).To(BeNil())
+			defer os.Setenv("GOPROXY", goProxy) //nolint // Test cleanup
+
+			completion, err := CompleteTool("example.invalid/tool/cmd/tool@latest", []string{""}, &Options{PkgRoot: tempDir})
+			Expect(err).To(BeNil())
+			Expect(completion).To(BeNil())
+
+			completion, err = CompleteTool("hello-world", []string{""}, &Options{
+				PkgRoot:   tempDir,
+				Overrides: map[string]string{"hello-world": "@0123456789ab"},
+			})
+			Expect(err).To(BeNil())
+			Expect(completion).To(BeNil())
+		})
+
+		It("should return an error for tools not in the tools file", func() {
+			_, err := CompleteTool("not-real", []string{""}, &Options{PkgRoot: tempDir})
+			Expect(err).ToNot(BeNil())
+		})
+	})
+})
@@ -0,0 +1,77 @@
+// Package gomodrun is the forgotten go tool that executes and caches binaries included in go.mod files.
+// This makes it easy to version cli tools in your projects such as `golangci-lint`
+// and `ginkgo` that are versioned locked to what you specify in `go.mod`.
+// Binaries are cached by go version and package version.
+package gomodrun
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigFileName is the name of the config file in the package root.
+const ConfigFileName = ".gomodrun.yml"
+
+// starterConfig is written by Init.
+const starterConfig = `# gomodrun configuration, see https://github.com/dustinblackman/gomodrun for details.
+
+# Build tags used to find the tools file.
+tags:
+  - tools
+
+# Version of gomodrun that committed shims go run when gomodrun is not installed.
+# gomodrun-version: v0.4.5
+
+# Environment variables set for a tool when they aren't already set.
+# tools:
+#   golangci-lint:
+#     env:
+#       GOGC: "50"
+#     # Commands run before and after the tool is built or run. Build hooks only run when the tool isn't cached.
+#     hooks:
+#       pre-run:
+#         - go generate ./...
+#       post-run:
+#         - echo "$GOMODRUN_TOOL $GOMODRUN_VERSION exited with $GOMODRUN_EXIT_CODE"
+`
+
+// Config contains project settings loaded from .gomodrun.yml in the package root.
+type Config struct {
+	Tags            []string              `yaml:"tags"`             // Build tags used to find the tools file. Defaults to tools.
+	GomodrunVersion string                `yaml:"gomodrun-version"` // Version of gomodrun committed shims `go run` when gomodrun isn't installed.
+	Hooks           HooksConfig           `yaml:"hooks"`            // Hooks run for every tool, before each tool's own hooks.
+	Tools           map[string]ToolConfig `yaml:"tools"`            // Settings for each tool by binary name.
+}
+
+// ToolConfig contains settings for a single tool.
+type ToolConfig struct {
+	Env   map[string]string `yaml:"env"`   // Environment variables set for the tool when they aren't already set.
+	Hooks HooksConfig       `yaml:"hooks"` // Hooks run for the tool.
+}
+
+// LoadConfig reads .gomodrun.yml from the package root, returning the defaults when it doesn't exist.
+func LoadConfig(pkgRoot string) (*Config, error) {
+	config := &Config{}
+
+	data, err := ioutil.ReadFile(path.Join(pkgRoot, ConfigFileName))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	if err == nil {
+		err = yaml.Unmarshal(data, config)
+		if err != nil {
+			return nil, fmt.Errorf("parsing %s failed: %s", ConfigFileName, err)
+		}
+	}
+
+	if len(config.Tags) == 0 {
+		config.Tags = []string{"tools"}
+	}
+
+	return config, nil
+}
@@ -0,0 +1,54 @@
+package gomodrun
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("config", func() {
+	var tempDir string
+
+	BeforeEach(func() {
+		var err error
+		tempDir, err = ioutil.TempDir(os.TempDir(), "gomodrun-config")
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	AfterEach(func() {
+		err := os.RemoveAll(tempDir)
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	It("should return defaults when the config file does not exist", func() {
+		config, err := LoadConfig(tempDir)
+		Expect(err).To(BeNil())
+		Expect(config.Tags).To(Equal([]string{"tools"}))
+	})
+
+	It("should find the tools file using the configured tags", func() {
+		err := ioutil.WriteFile(path.Join(tempDir, ConfigFileName), []byte("tags:\n  - devtools\n"), 0600)
+		Expect(err).To(BeNil())
+		err = ioutil.WriteFile(path.Join(tempDir, "devtools.go"), []byte("// +build devtools\n\npackage app\n\nimport _ \"mvdan.cc/gofumpt\"\n"), 0600)
+		Expect(err).To(BeNil())
+
+		binNames, err := GetToolBinNames(tempDir)
+		Expect(err).To(BeNil())
+		Expect(binNames).To(Equal([]string{"gofumpt"}))
+	})
+
+	It("should return an error when the config file is invalid", func() {
+		err := ioutil.WriteFile(path.Join(tempDir, ConfigFileName), []byte("tags: ["), 0600)
+		Expect(err).To(BeNil())
+
+		_, err = LoadConfig(tempDir)
+		Expect(err).ToNot(BeNil())
+	})
+})
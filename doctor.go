@@ -0,0 +1,259 @@
+// Package gomodrun is the forgotten go tool that executes and caches binaries included in go.mod files.
+// This makes it easy to version cli tools in your projects such as `golangci-lint`
+// and `ginkgo` that are versioned locked to what you specify in `go.mod`.
+// Binaries are cached by go version and package version.
+package gomodrun
+
+import (
+	"debug/buildinfo"
+	"fmt"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/mod/semver"
+)
+
+// Statuses of a doctor check.
+const (
+	CheckPass = "pass"
+	CheckWarn = "warn"
+	CheckFail = "fail"
+)
+
+// Check is the result of a single diagnostic run by Doctor.
+type Check struct {
+	Name    string `json:"name"`           // What was checked.
+	Status  string `json:"status"`         // One of pass, warn or fail.
+	Message string `json:"message"`        // Details of what was found.
+	Hint    string `json:"hint,omitempty"` // How to fix a warning or failure.
+}
+
+type doctor struct {
+	pkgRoot   string
+	goVersion string
+	checks    []*Check
+}
+
+func (d *doctor) add(name, status, message, hint string) {
+	d.checks = append(d.checks, &Check{
+		Name:    name,
+		Status:  status,
+		Message: message,
+		Hint:    hint,
+	})
+}
+
+// checkWritable returns an error if a file can't be created in dir, or the nearest parent that exists.
+func checkWritable(dir string) error {
+	for {
+		if _, err := os.Stat(dir); err == nil || path.Dir(dir) == dir {
+			break
+		}
+		dir = filepath.Dir(dir)
+	}
+
+	file, err := ioutil.TempFile(dir, ".gomodrun-doctor")
+	if err != nil {
+		return err
+	}
+	file.Close()
+
+	return os.Remove(file.Name())
+}
+
+func (d *doctor) checkGo(goModVersion string) {
+	goPath, err := exec.LookPath("go")
+	if err != nil {
+		d.add("go on PATH", CheckFail, "go was not found on PATH", "install go from https://go.dev/dl and add it to PATH")
+		return
+	}
+
+	d.goVersion, err = getGoVersion()
+	if err != nil {
+		d.add("go on PATH", CheckFail, fmt.Sprintf("running %s version failed: %s", goPath, err), "reinstall go from https://go.dev/dl")
+		return
+	}
+	d.add("go on PATH", CheckPass, fmt.Sprintf("%s is %s", goPath, d.goVersion), "")
+
+	if goModVersion == "" {
+		return
+	}
+
+	if semver.Compare(goVersionToSemver(d.goVersion), goVersionToSemver("go"+goModVersion)) < 0 {
+		d.add("go version", CheckFail, fmt.Sprintf("%s is older than go %s required by go.mod", d.goVersion, goModVersion), "upgrade go to at least go"+goModVersion)
+		return
+	}
+	d.add("go version", CheckPass, fmt.Sprintf("%s satisfies go %s in go.mod", d.goVersion, goModVersion), "")
+}
+
+func (d *doctor) checkGoEnv() {
+	for _, key := range []string{"GOPATH", "GOMODCACHE"} {
+		value, err := getGoEnv(key)
+		if err != nil || value == "" {
+			d.add(key, CheckFail, fmt.Sprintf("go env %s is not set", key), "check your go installation with go env")
+			continue
+		}
+
+		if err = checkWritable(value); err != nil {
+			d.add(key, CheckFail, fmt.Sprintf("%s is not writable: %s", value, err), fmt.Sprintf("fix the permissions of %s or set %s to a writable directory", value, key))
+			continue
+		}
+		d.add(key, CheckPass, fmt.Sprintf("%s is writable", value), "")
+	}
+
+	goProxy, err := getGoEnv("GOPROXY")
+	switch {
+	case err != nil:
+		d.add("GOPROXY", CheckFail, fmt.Sprintf("reading go env GOPROXY failed: %s", err), "check your go installation with go env")
+	case goProxy == "off":
+		d.add("GOPROXY", CheckWarn, "GOPROXY=off, modules that are not in the module cache can not be downloaded", "unset GOPROXY or set it to https://proxy.golang.org,direct")
+	default:
+		d.add("GOPROXY", CheckPass, "GOPROXY="+goProxy, "")
+	}
+
+	goFlags, err := getGoEnv("GOFLAGS")
+	switch {
+	case err != nil:
+		d.add("GOFLAGS", CheckFail, fmt.Sprintf("reading go env GOFLAGS failed: %s", err), "check your go installation with go env")
+	case strings.Contains(goFlags, "-mod=vendor"):
+		d.add("GOFLAGS", CheckWarn, "GOFLAGS="+goFlags+", tools are built from the module cache and not vendor", "remove -mod=vendor from GOFLAGS when running gomodrun")
+	default:
+		d.add("GOFLAGS", CheckPass, "GOFLAGS="+goFlags, "")
+	}
+}
+
+func (d *doctor) checkTools() {
+	config, err := LoadConfig(d.pkgRoot)
+	if err != nil {
+		d.add("config", CheckFail, err.Error(), fmt.Sprintf("fix the syntax of %s", ConfigFileName))
+		return
+	}
+
+	toolsFile, err := getToolsFile(d.pkgRoot)
+	if err != nil || toolsFile == "" {
+		d.add("tools file", CheckFail, fmt.Sprintf("no file with the %s build tags found in %s", strings.Join(config.Tags, ","), d.pkgRoot), "run gomodrun init to create a tools file")
+		return
+	}
+
+	_, err = parser.ParseFile(token.NewFileSet(), toolsFile, nil, parser.ImportsOnly)
+	if err != nil {
+		d.add("tools file", CheckFail, fmt.Sprintf("parsing %s failed: %s", toolsFile, err), "fix the syntax errors in the tools file")
+		return
+	}
+	d.add("tools file", CheckPass, fmt.Sprintf("%s found with the %s build tags", toolsFile, strings.Join(config.Tags, ",")), "")
+
+	pkg, err := getToolsPkg(d.pkgRoot)
+	if err != nil {
+		d.add("tools file", CheckFail, err.Error(), "fix the errors in the tools file")
+		return
+	}
+
+	mod, err := getGoMod(d.pkgRoot)
+	if err != nil {
+		return
+	}
+
+	missing := []string{}
+	for _, importPath := range pkg.Imports {
+		if getRequire(mod, importPath) == nil {
+			missing = append(missing, importPath)
+		}
+	}
+
+	if len(missing) > 0 {
+		d.add("tool requires", CheckFail, fmt.Sprintf("no require in go.mod for %s", strings.Join(missing, ", ")), "run go mod tidy to add the missing requires")
+		return
+	}
+	d.add("tool requires", CheckPass, fmt.Sprintf("all %d tools are required in go.mod", len(pkg.Imports)), "")
+}
+
+func (d *doctor) checkCache() {
+	gmrRoot := path.Join(d.pkgRoot, ".gomodrun")
+	if err := checkWritable(gmrRoot); err != nil {
+		d.add("cache directory", CheckFail, fmt.Sprintf("%s is not writable: %s", gmrRoot, err), fmt.Sprintf("fix the permissions of %s", gmrRoot))
+		return
+	}
+	d.add("cache directory", CheckPass, fmt.Sprintf("%s is writable", gmrRoot), "")
+
+	if _, err := os.Stat(gmrRoot); os.IsNotExist(err) || d.goVersion == "" {
+		return
+	}
+
+	report, err := TidyWithReport(d.pkgRoot, &TidyOptions{DryRun: true})
+	if err != nil {
+		d.add("stale binaries", CheckFail, fmt.Sprintf("inspecting %s failed: %s", gmrRoot, err), "")
+		return
+	}
+
+	if len(report.Removed) > 0 {
+		d.add("stale binaries", CheckWarn, fmt.Sprintf("%d stale entries are using %d bytes", len(report.Removed), report.ReclaimedBytes()), "run gomodrun --tidy to remove them")
+	} else {
+		d.add("stale binaries", CheckPass, "no stale binaries found", "")
+	}
+
+	valid := 0
+	corrupted := []string{}
+	for _, entry := range report.Kept {
+		if isSidecar(entry.Path) {
+			continue
+		}
+
+		info, err := buildinfo.ReadFile(entry.Path)
+		if err != nil || !strings.Contains(filepath.ToSlash(entry.Path), "/"+info.GoVersion+"/") {
+			corrupted = append(corrupted, entry.Path)
+			continue
+		}
+		valid++
+	}
+
+	if len(corrupted) > 0 {
+		d.add("cached binaries", CheckFail, fmt.Sprintf("corrupted binaries found: %s", strings.Join(corrupted, ", ")), "delete the binaries and they'll be rebuilt on the next run")
+		return
+	}
+	d.add("cached binaries", CheckPass, fmt.Sprintf("%d cached binaries are valid", valid), "")
+}
+
+// Doctor runs diagnostics on the go installation, the tools file and the .gomodrun cache, returning the result of
+// each check along with hints on how to fix any failures.
+func Doctor(pkgRoot string) []*Check {
+	d := &doctor{
+		pkgRoot: pkgRoot,
+		checks:  []*Check{},
+	}
+
+	if d.pkgRoot == "" {
+		var err error
+		d.pkgRoot, err = GetPkgRoot()
+		if err != nil {
+			d.checkGo("")
+			d.add("go.mod", CheckFail, err.Error(), "run gomodrun from inside a go module, or pass the root with --pkg-root")
+			return d.checks
+		}
+	}
+
+	goModVersion := ""
+	mod, err := getGoMod(d.pkgRoot)
+	if err == nil && mod.Go != nil {
+		goModVersion = mod.Go.Version
+	}
+
+	d.checkGo(goModVersion)
+	d.checkGoEnv()
+
+	if err != nil {
+		d.add("go.mod", CheckFail, fmt.Sprintf("reading go.mod failed: %s", err), "fix the errors in go.mod")
+		return d.checks
+	}
+	d.add("go.mod", CheckPass, fmt.Sprintf("%s found", path.Join(d.pkgRoot, "go.mod")), "")
+
+	d.checkTools()
+	d.checkCache()
+
+	return d.checks
+}
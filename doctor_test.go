@@ -0,0 +1,111 @@
+package gomodrun
+
+import (
+	"os"
+	"path"
+	"runtime"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func getCheck(checks []*Check, name string) *Check {
+	for _, check := range checks {
+		if check.Name == name {
+			return check
+		}
+	}
+
+	return nil
+}
+
+var _ = Describe("doctor", func() {
+	var tempDir string
+	var goVersion string
+
+	//nolint:dogsled // Test file, don't need any of the extra values
+	_, filename, _, _ := runtime.Caller(0)
+	testsDir := path.Join(path.Dir(filename), "tests")
+
+	BeforeEach(func() {
+		var err error
+		goVersion, err = getGoVersion()
+		if err != nil {
+			panic(err)
+		}
+
+		tempDir = createProjectFixture()
+	})
+
+	AfterEach(func() {
+		err := os.RemoveAll(tempDir)
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	It("should pass the go, go.mod and tools file checks", func() {
+		checks := Doctor(tempDir)
+
+		for _, name := range []string{"go on PATH", "go version", "GOPATH", "GOMODCACHE", "go.mod", "tools file", "tool requires", "cache directory"} {
+			check := getCheck(checks, name)
+			Expect(check).ToNot(BeNil(), name)
+			Expect(check.Status).To(Equal(CheckPass), name)
+		}
+
+		Expect(getCheck(checks, "go on PATH").Message).To(ContainSubstring(goVersion))
+	})
+
+	It("should warn about stale binaries and fail on corrupted binaries", func() {
+		addTidyBins(tempDir, goVersion)
+		checks := Doctor(tempDir)
+
+		stale := getCheck(checks, "stale binaries")
+		Expect(stale.Status).To(Equal(CheckWarn))
+		Expect(stale.Message).To(ContainSubstring("2 stale entries"))
+		Expect(stale.Hint).To(ContainSubstring("--tidy"))
+
+		corrupted := getCheck(checks, "cached binaries")
+		Expect(corrupted.Status).To(Equal(CheckFail))
+		Expect(corrupted.Message).To(ContainSubstring(path.Join(goVersion, "github.com/dustinblackman/go-hello-world-test@v0.0.2/hello-world/hello-world")))
+	})
+
+	It("should pass binaries built by gomodrun", func() {
+		_, err := GetCachedBin(tempDir, "hello-world", "github.com/dustinblackman/go-hello-world-test@v0.0.2/hello-world")
+		Expect(err).To(BeNil())
+
+		checks := Doctor(tempDir)
+		Expect(getCheck(checks, "stale binaries").Status).To(Equal(CheckPass))
+		Expect(getCheck(checks, "cached binaries").Status).To(Equal(CheckPass))
+		Expect(getCheck(checks, "cached binaries").Message).To(ContainSubstring("1 cached binaries"))
+	})
+
+	It("should fail when a tool is not required in go.mod", func() {
+		check := getCheck(Doctor(path.Join(testsDir, "incomplete-go-mod")), "tool requires")
+		Expect(check.Status).To(Equal(CheckFail))
+		Expect(check.Message).To(ContainSubstring("github.com/dustinblackman/go-hello-world-test/hello-world"))
+		Expect(check.Hint).To(ContainSubstring("go mod tidy"))
+	})
+
+	It("should fail when go.mod can not be read", func() {
+		checks := Doctor(path.Join(testsDir, "corrupted-go-mod"))
+		Expect(getCheck(checks, "go.mod").Status).To(Equal(CheckFail))
+		Expect(getCheck(checks, "tools file")).To(BeNil())
+	})
+
+	It("should warn when GOPROXY is off", func() {
+		goProxy, hasGoProxy := os.LookupEnv("GOPROXY")
+		os.Setenv("GOPROXY", "off")
+		defer func() {
+			if hasGoProxy {
+				os.Setenv("GOPROXY", goProxy)
+			} else {
+				os.Unsetenv("GOPROXY")
+			}
+		}()
+
+		check := getCheck(Doctor(tempDir), "GOPROXY")
+		Expect(check.Status).To(Equal(CheckWarn))
+		Expect(check.Hint).ToNot(BeEmpty())
+	})
+})
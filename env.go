@@ -0,0 +1,47 @@
+// Package gomodrun is the forgotten go tool that executes and caches binaries included in go.mod files.
+// This makes it easy to version cli tools in your projects such as `golangci-lint`
+// and `ginkgo` that are versioned locked to what you specify in `go.mod`.
+// Binaries are cached by go version and package version.
+package gomodrun
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Shells supported by GetShellEnv.
+var Shells = []string{"bash", "zsh", "fish", "powershell"}
+
+// Escapers for values written inside double quotes of each shell.
+var (
+	posixEscaper      = strings.NewReplacer(`\`, `\\`, `"`, `\"`, `$`, `\$`, "`", "\\`")
+	fishEscaper       = strings.NewReplacer(`\`, `\\`, `"`, `\"`, `$`, `\$`)
+	powershellEscaper = strings.NewReplacer("`", "``", `"`, "`\"", `$`, "`$")
+)
+
+// GetShellEnv returns commands for the given shell that add dir to the front of PATH, suitable for `eval` or direnv.
+// Other POSIX shells such as sh and dash get the same commands as bash.
+func GetShellEnv(shell, dir string) (string, error) {
+	switch shell {
+	case "bash", "zsh", "sh", "dash", "ash", "ksh":
+		return fmt.Sprintf("export PATH=\"%s%s$PATH\"\n", posixEscaper.Replace(dir), string(os.PathListSeparator)), nil
+	case "fish":
+		return fmt.Sprintf("set -gx PATH \"%s\" $PATH\n", fishEscaper.Replace(dir)), nil
+	case "powershell", "pwsh":
+		return fmt.Sprintf("$env:PATH = \"%s%s\" + $env:PATH\n", powershellEscaper.Replace(dir), string(os.PathListSeparator)), nil
+	}
+
+	return "", fmt.Errorf("unsupported shell %s, expected one of %s", shell, strings.Join(Shells, ", "))
+}
+
+// Env writes shims for every tool in the tools file and returns commands for the given shell that add them to the
+// front of PATH.
+func Env(pkgRoot, shell string, options *ShimOptions) (string, error) {
+	shimDir, err := CreateShimDir(pkgRoot, options)
+	if err != nil {
+		return "", err
+	}
+
+	return GetShellEnv(shell, shimDir)
+}
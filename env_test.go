@@ -0,0 +1,92 @@
+package gomodrun
+
+import (
+	"os"
+	"os/exec"
+	"path"
+	"runtime"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("env", func() {
+	separator := string(os.PathListSeparator)
+
+	Context("GetShellEnv", func() {
+		It("should export PATH for bash and zsh", func() {
+			for _, shell := range []string{"bash", "zsh"} {
+				env, err := GetShellEnv(shell, "/tools")
+				Expect(err).To(BeNil())
+				Expect(env).To(Equal("export PATH=\"/tools" + separator + "$PATH\"\n"))
+			}
+		})
+
+		It("should set PATH for fish", func() {
+			env, err := GetShellEnv("fish", "/tools")
+			Expect(err).To(BeNil())
+			Expect(env).To(Equal("set -gx PATH \"/tools\" $PATH\n"))
+		})
+
+		It("should set PATH for powershell", func() {
+			env, err := GetShellEnv("powershell", "/tools")
+			Expect(err).To(BeNil())
+			Expect(env).To(Equal("$env:PATH = \"/tools" + separator + "\" + $env:PATH\n"))
+		})
+
+		It("should export PATH for other POSIX shells", func() {
+			for _, shell := range []string{"sh", "dash"} {
+				env, err := GetShellEnv(shell, "/tools")
+				Expect(err).To(BeNil())
+				Expect(env).To(Equal("export PATH=\"/tools" + separator + "$PATH\"\n"))
+			}
+		})
+
+		It("should escape quotes, variables and backticks in the directory", func() {
+			dir := "/tools/a\"b$c`d\\e"
+
+			env, err := GetShellEnv("sh", dir)
+			Expect(err).To(BeNil())
+			Expect(env).To(Equal("export PATH=\"/tools/a\\\"b\\$c\\`d\\\\e" + separator + "$PATH\"\n"))
+
+			env, err = GetShellEnv("fish", dir)
+			Expect(err).To(BeNil())
+			Expect(env).To(Equal("set -gx PATH \"/tools/a\\\"b\\$c`d\\\\e\" $PATH\n"))
+
+			env, err = GetShellEnv("powershell", dir)
+			Expect(err).To(BeNil())
+			Expect(env).To(Equal("$env:PATH = \"/tools/a`\"b`$c``d\\e" + separator + "\" + $env:PATH\n"))
+
+			if runtime.GOOS == "windows" {
+				return
+			}
+
+			env, err = GetShellEnv("sh", dir)
+			Expect(err).To(BeNil())
+			output, err := exec.Command("sh", "-c", "PATH=/usr/bin; "+env+"printf %s \"$PATH\"").Output()
+			Expect(err).To(BeNil())
+			Expect(string(output)).To(Equal(dir + separator + "/usr/bin"))
+		})
+
+		It("should return an error for unsupported shells", func() {
+			env, err := GetShellEnv("tcsh", "/tools")
+			Expect(err).ToNot(BeNil())
+			Expect(env).To(Equal(""))
+		})
+	})
+
+	Context("Env", func() {
+		It("should write shims and return the shim directory export", func() {
+			//nolint:dogsled // Test file, don't need any of the extra values
+			_, filename, _, _ := runtime.Caller(0)
+			pkgRoot := path.Join(path.Dir(filename), "tests/alternative-tools-dir")
+			defer os.RemoveAll(path.Join(pkgRoot, ".gomodrun")) //nolint // Test cleanup
+
+			env, err := Env(pkgRoot, "bash", &ShimOptions{})
+			Expect(err).To(BeNil())
+			Expect(env).To(HavePrefix("export PATH=\"" + path.Join(pkgRoot, ".gomodrun", shimsDirName) + separator))
+			Expect(strings.Count(env, "\n")).To(Equal(1))
+		})
+	})
+})
@@ -0,0 +1,132 @@
+// Package gomodrun is the forgotten go tool that executes and caches binaries included in go.mod files.
+// This makes it easy to version cli tools in your projects such as `golangci-lint`
+// and `ginkgo` that are versioned locked to what you specify in `go.mod`.
+// Binaries are cached by go version and package version.
+package gomodrun
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"golang.org/x/mod/semver"
+)
+
+// ephemeralDirName is the directory in each go version directory containing binaries of tools run as
+// module/pkg@version. Tidy keeps them as they aren't in go.mod, leaving them to gc.
+const ephemeralDirName = "ephemeral"
+
+// isEphemeral returns true for tools given as module/pkg@version rather than a binary name in the tools file.
+func isEphemeral(tool string) bool {
+	return strings.Contains(tool, "@")
+}
+
+// isEphemeralBin returns true for cached binaries in cacheDir built for tools run as module/pkg@version.
+func isEphemeralBin(cacheDir, binPath string) bool {
+	relPath, err := filepath.Rel(cacheDir, binPath)
+	if err != nil {
+		return false
+	}
+
+	parts := strings.Split(filepath.ToSlash(relPath), "/")
+	return len(parts) > 2 && parts[1] == ephemeralDirName
+}
+
+// getModuleCandidates returns every module path that could provide importPath, longest first.
+func getModuleCandidates(importPath string) []string {
+	candidates := []string{}
+	for candidate := importPath; candidate != "." && candidate != "/"; candidate = path.Dir(candidate) {
+		candidates = append(candidates, candidate)
+	}
+
+	return candidates
+}
+
+// setEphemeralModule fills in the module, version and paths of resolution for importPath provided by module.
+func (r *Runner) setEphemeralModule(resolution *Resolution, module, version string) error {
+	resolution.Module = module
+	resolution.Version = version
+	resolution.Subpackage = strings.TrimPrefix(strings.TrimPrefix(resolution.ImportPath, module), "/")
+	resolution.CmdPath = path.Join(module+"@"+version, resolution.Subpackage)
+
+	var err error
+	resolution.CachePath, err = r.cache().BinPath(resolution.GoVersion, path.Join(ephemeralDirName, resolution.CmdPath), resolution.BinName)
+	return err
+}
+
+// resolveEphemeral resolves a tool given as module/pkg@version that isn't in the tools file, such as
+// golang.org/x/tools/cmd/deadcode@v0.20.0. The module is downloaded outside of the package, leaving go.mod untouched,
+// and the tool is built with the dependencies in its own go.mod. Without download, only exact versions that are already
+// built are resolved and nil is returned for anything else.
+func (r *Runner) resolveEphemeral(tool string, download bool) (*Resolution, error) {
+	importPath, version, _ := strings.Cut(tool, "@")
+	if importPath == "" || version == "" {
+		return nil, fmt.Errorf("invalid tool %s, expected module/pkg@version", tool)
+	}
+
+	resolution := &Resolution{
+		ImportPath: importPath,
+		BinName:    getBinName(importPath),
+		Ephemeral:  true,
+	}
+	if runtime.GOOS == "windows" {
+		resolution.BinName += ".exe"
+	}
+
+	var err error
+	resolution.GoVersion, err = r.toolchain().Version()
+	if err != nil {
+		return nil, err
+	}
+
+	// Exact versions that are already built don't need to be downloaded to find their module.
+	if semver.Canonical(version) == version {
+		for _, module := range getModuleCandidates(importPath) {
+			err = r.setEphemeralModule(resolution, module, version)
+			if err != nil {
+				return nil, err
+			}
+
+			if r.cache().Exists(resolution.CachePath) {
+				resolution.SourceDir = getModuleCacheDir(resolution.CmdPath)
+				return resolution, nil
+			}
+		}
+	}
+
+	if !download {
+		return nil, nil
+	}
+
+	var downloadErr error
+	for _, module := range getModuleCandidates(importPath) {
+		start := time.Now()
+		download, err := r.downloadModule(module + "@" + version)
+		if err != nil {
+			if downloadErr == nil {
+				downloadErr = err
+			}
+			continue
+		}
+
+		err = r.setEphemeralModule(resolution, module, download.Version)
+		if err != nil {
+			return nil, err
+		}
+		resolution.Sum = download.Sum
+		resolution.SourceDir = path.Join(filepath.ToSlash(download.Dir), resolution.Subpackage)
+
+		if info, err := os.Stat(resolution.SourceDir); err != nil || !info.IsDir() {
+			return nil, fmt.Errorf("cant find package %s in module %s@%s", importPath, module, download.Version)
+		}
+		logTiming(r.Logger, start, "downloaded %s@%s", module, download.Version)
+
+		return resolution, nil
+	}
+
+	return nil, fmt.Errorf("downloading %s failed: %s", tool, downloadErr)
+}
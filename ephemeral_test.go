@@ -0,0 +1,101 @@
+package gomodrun
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ephemeral", func() {
+	var tempDir string
+	var downloadDir string
+	var toolchain *fakeToolchain
+	var runner *Runner
+
+	BeforeEach(func() {
+		tempDir = createProjectFixture()
+		downloadDir = path.Join(tempDir, "download", "tools@v0.20.0")
+		err := os.MkdirAll(path.Join(downloadDir, "cmd", "deadcode"), 0750)
+		Expect(err).To(BeNil())
+		err = ioutil.WriteFile(path.Join(downloadDir, "go.mod"), []byte("module golang.org/x/tools\n"), 0600)
+		Expect(err).To(BeNil())
+
+		toolchain = &fakeToolchain{
+			downloads: map[string]string{
+				"golang.org/x/tools@v0.20.0": `{"Path": "golang.org/x/tools", "Version": "v0.20.0", "Dir": "` + downloadDir + `", "Sum": "h1:abc="}`,
+				"golang.org/x/tools@latest":  `{"Path": "golang.org/x/tools", "Version": "v0.20.0", "Dir": "` + downloadDir + `", "Sum": "h1:abc="}`,
+			},
+		}
+		runner, err = NewRunner(&Options{PkgRoot: tempDir})
+		Expect(err).To(BeNil())
+		runner.Toolchain = toolchain
+		runner.Sources = &fakeSources{dir: path.Join(tempDir, "src")}
+	})
+
+	AfterEach(func() {
+		err := os.RemoveAll(tempDir)
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	It("should find the module providing the package", func() {
+		Expect(getModuleCandidates("golang.org/x/tools/cmd/deadcode")).To(Equal([]string{
+			"golang.org/x/tools/cmd/deadcode",
+			"golang.org/x/tools/cmd",
+			"golang.org/x/tools",
+			"golang.org/x",
+			"golang.org",
+		}))
+
+		resolution, err := runner.Resolve("golang.org/x/tools/cmd/deadcode@latest")
+		Expect(err).To(BeNil())
+		Expect(resolution).To(Equal(&Resolution{
+			ImportPath: "golang.org/x/tools/cmd/deadcode",
+			Module:     "golang.org/x/tools",
+			Version:    "v0.20.0",
+			Subpackage: "cmd/deadcode",
+			Sum:        "h1:abc=",
+			CmdPath:    "golang.org/x/tools@v0.20.0/cmd/deadcode",
+			SourceDir:  path.Join(downloadDir, "cmd", "deadcode"),
+			BinName:    "deadcode",
+			GoVersion:  "go9.9.9",
+			CachePath:  path.Join(tempDir, ".gomodrun", "go9.9.9", "ephemeral", "golang.org/x/tools@v0.20.0/cmd/deadcode", "deadcode"),
+			Ephemeral:  true,
+		}))
+	})
+
+	It("should build with the tool's own go.mod and skip downloads once cached", func() {
+		exitCode, err := runner.Run("golang.org/x/tools/cmd/deadcode@v0.20.0", []string{})
+		Expect(err).To(BeNil())
+		Expect(exitCode).To(Equal(3))
+		Expect(toolchain.calls).To(HaveLen(4))
+		Expect(toolchain.calls[3]).To(HavePrefix("build -trimpath -o " + path.Join(tempDir, ".gomodrun", "go9.9.9", "ephemeral", "golang.org/x/tools@v0.20.0")))
+		Expect(toolchain.dirs[3]).To(Equal(path.Join(downloadDir, "cmd", "deadcode")))
+
+		_, err = runner.Run("golang.org/x/tools/cmd/deadcode@v0.20.0", []string{})
+		Expect(err).To(BeNil())
+		Expect(toolchain.calls).To(HaveLen(4))
+	})
+
+	It("should keep binaries of module/pkg@version runs when tidying", func() {
+		_, err := runner.Run("golang.org/x/tools/cmd/deadcode@v0.20.0", []string{})
+		Expect(err).To(BeNil())
+
+		report, err := runner.Tidy(&TidyOptions{})
+		Expect(err).To(BeNil())
+		Expect(report.Kept).To(ContainElement(HaveField("Path", path.Join(tempDir, ".gomodrun", "go9.9.9", "ephemeral", "golang.org/x/tools@v0.20.0/cmd/deadcode", "deadcode"))))
+		Expect(report.Removed).ToNot(ContainElement(HaveField("Path", ContainSubstring(ephemeralDirName))))
+	})
+
+	It("should return an error when no module provides the package", func() {
+		_, err := runner.Resolve("example.com/nope@v1.0.0")
+		Expect(err).To(MatchError("downloading example.com/nope@v1.0.0 failed: example.com/nope@v1.0.0: not found"))
+
+		_, err = runner.Resolve("@v1.0.0")
+		Expect(err).To(MatchError("invalid tool @v1.0.0, expected module/pkg@version"))
+	})
+})
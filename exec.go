@@ -0,0 +1,186 @@
+// Package gomodrun is the forgotten go tool that executes and caches binaries included in go.mod files.
+// This makes it easy to version cli tools in your projects such as `golangci-lint`
+// and `ginkgo` that are versioned locked to what you specify in `go.mod`.
+// Binaries are cached by go version and package version.
+package gomodrun
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// binDirName is the directory in .gomodrun containing links to every tool in the tools file.
+const binDirName = "bin"
+
+// BuildAll builds every tool in the tools file, returning a map of binary names to cached binary paths.
+func BuildAll(pkgRoot string) (map[string]string, error) {
+	return (&Runner{Options: Options{PkgRoot: pkgRoot}}).BuildAll()
+}
+
+// BuildAll builds every tool in the tools file, returning a map of binary names to cached binary paths.
+func (r *Runner) BuildAll() (map[string]string, error) {
+	binNames, err := r.List()
+	if err != nil {
+		return nil, err
+	}
+
+	cachedBins := map[string]string{}
+	for _, binName := range binNames {
+		cachedBin, err := r.Build(binName)
+		if err != nil {
+			return nil, err
+		}
+		cachedBins[binName] = cachedBin
+	}
+
+	return cachedBins, nil
+}
+
+// linkBin links binPath to linkPath, replacing any existing link. The link is created under a unique temporary name
+// first so concurrent runs don't replace each other's links part way through.
+func linkBin(binPath, linkPath string) error {
+	tempFile, err := ioutil.TempFile(path.Dir(linkPath), "."+path.Base(linkPath)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tempPath := tempFile.Name()
+	tempFile.Close()
+	os.Remove(tempPath) //nolint // Ignore error, only the unique name is needed.
+
+	err = os.Symlink(binPath, tempPath)
+	if err != nil {
+		// Symlinks require additional privileges on Windows, fall back to hard links.
+		err = os.Link(binPath, tempPath)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = os.Rename(tempPath, linkPath)
+	if err != nil {
+		os.Remove(tempPath) //nolint // Ignore error, the rename error is more useful.
+	}
+
+	return err
+}
+
+// pruneBinDir removes links from binDir for tools that are no longer in the tools file, leaving temporary links of
+// concurrent runs in place.
+func pruneBinDir(binDir string, linkNames map[string]bool) error {
+	files, err := ioutil.ReadDir(binDir)
+	if err != nil {
+		return err
+	}
+
+	for _, file := range files {
+		if linkNames[file.Name()] || (strings.HasPrefix(file.Name(), ".") && strings.HasSuffix(file.Name(), ".tmp")) {
+			continue
+		}
+
+		err = os.Remove(path.Join(binDir, file.Name()))
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// CreateBinDir builds every tool in the tools file and links them in to a single directory, returning the directory path.
+func CreateBinDir(pkgRoot string) (string, error) {
+	return (&Runner{Options: Options{PkgRoot: pkgRoot}}).createBinDir()
+}
+
+func (r *Runner) createBinDir() (string, error) {
+	cachedBins, err := r.BuildAll()
+	if err != nil {
+		return "", err
+	}
+
+	binDir, err := filepath.Abs(path.Join(r.cacheDir(), binDirName))
+	if err != nil {
+		return "", err
+	}
+
+	err = os.MkdirAll(binDir, os.ModePerm)
+	if err != nil {
+		return "", err
+	}
+
+	linkNames := map[string]bool{}
+	for binName, cachedBin := range cachedBins {
+		if runtime.GOOS == "windows" && !strings.HasSuffix(binName, ".exe") {
+			binName += ".exe"
+		}
+
+		err = linkBin(cachedBin, path.Join(binDir, binName))
+		if err != nil {
+			return "", err
+		}
+		linkNames[binName] = true
+	}
+
+	err = pruneBinDir(binDir, linkNames)
+	if err != nil {
+		return "", err
+	}
+
+	return binDir, nil
+}
+
+// prependPath returns a copy of env with dir added to the front of PATH.
+func prependPath(env []string, dir string) []string {
+	result := []string{}
+	found := false
+	for _, entry := range env {
+		parts := strings.SplitN(entry, "=", 2)
+		if len(parts) == 2 && strings.EqualFold(parts[0], "PATH") && !found {
+			entry = parts[0] + "=" + dir + string(os.PathListSeparator) + parts[1]
+			found = true
+		}
+		result = append(result, entry)
+	}
+
+	if !found {
+		result = append(result, "PATH="+dir)
+	}
+
+	return result
+}
+
+// Exec executes your binary with every other tool in the tools file added to the front of PATH, allowing tools that
+// call other tools by name to use the versions in go.mod.
+func Exec(binName string, args []string, options *Options) (int, error) {
+	runner, err := NewRunner(options)
+	if err != nil {
+		return -1, err
+	}
+
+	return runner.Exec(binName, args)
+}
+
+// Exec executes your binary with every other tool in the tools file added to the front of PATH.
+func (r *Runner) Exec(binName string, args []string) (int, error) {
+	start := time.Now()
+	binDir, err := r.createBinDir()
+	if err != nil {
+		return -1, err
+	}
+	logTiming(r.Logger, start, "linked every tool in to %s", binDir)
+
+	env, err := r.getToolEnv(binName)
+	if err != nil {
+		return -1, err
+	}
+
+	runner := *r
+	runner.Env = prependPath(env, binDir)
+	runner.ExtraEnv = nil
+
+	return runner.Run(binName, args)
+}
@@ -0,0 +1,127 @@
+package gomodrun
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"runtime"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/otiai10/copy"
+)
+
+var _ = Describe("exec", func() {
+	var tempDir string
+
+	BeforeEach(func() {
+		var err error
+		tempDir, err = ioutil.TempDir(os.TempDir(), "gomodrun-exec")
+		if err != nil {
+			panic(err)
+		}
+
+		//nolint:dogsled // Test file, don't need any of the extra values
+		_, filename, _, _ := runtime.Caller(0)
+		err = copy.Copy(path.Join(path.Dir(filename), "./tests/alternative-tools-dir"), tempDir)
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	AfterEach(func() {
+		err := os.RemoveAll(tempDir)
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	Context("prependPath", func() {
+		It("should add the directory to the front of PATH", func() {
+			env := prependPath([]string{"HOME=/home/test", "PATH=/usr/bin"}, "/tools")
+			Expect(env).To(Equal([]string{"HOME=/home/test", "PATH=/tools" + string(os.PathListSeparator) + "/usr/bin"}))
+		})
+
+		It("should add PATH when it is missing", func() {
+			env := prependPath([]string{"HOME=/home/test"}, "/tools")
+			Expect(env).To(Equal([]string{"HOME=/home/test", "PATH=/tools"}))
+		})
+	})
+
+	Context("GetToolBinNames", func() {
+		It("should return the binary names in the tools file", func() {
+			binNames, err := GetToolBinNames(tempDir)
+			Expect(err).To(BeNil())
+			Expect(binNames).To(Equal([]string{"hello-world"}))
+		})
+	})
+
+	Context("CreateBinDir", func() {
+		It("should link every tool in to the bin directory", func() {
+			binDir, err := CreateBinDir(tempDir)
+			Expect(err).To(BeNil())
+			Expect(binDir).To(HaveSuffix(path.Join(".gomodrun", binDirName)))
+
+			_, err = os.Stat(path.Join(binDir, "hello-world"))
+			Expect(err).To(BeNil())
+		})
+
+		It("should remove links to tools no longer in the tools file", func() {
+			binDir := path.Join(tempDir, ".gomodrun", binDirName)
+			err := os.MkdirAll(binDir, 0750)
+			Expect(err).To(BeNil())
+			err = os.Symlink(path.Join(tempDir, "missing"), path.Join(binDir, "removed-tool"))
+			Expect(err).To(BeNil())
+
+			_, err = CreateBinDir(tempDir)
+			Expect(err).To(BeNil())
+
+			files, err := ioutil.ReadDir(binDir)
+			Expect(err).To(BeNil())
+			Expect(files).To(HaveLen(1))
+			Expect(files[0].Name()).To(Equal("hello-world"))
+		})
+
+		It("should not be removed by tidy", func() {
+			binDir, err := CreateBinDir(tempDir)
+			Expect(err).To(BeNil())
+
+			err = Tidy(tempDir)
+			Expect(err).To(BeNil())
+
+			_, err = os.Stat(path.Join(binDir, "hello-world"))
+			Expect(err).To(BeNil())
+		})
+	})
+
+	Context("linkBin", func() {
+		It("should replace an existing link", func() {
+			linkPath := path.Join(tempDir, "link")
+			err := linkBin(path.Join(tempDir, "go.mod"), linkPath)
+			Expect(err).To(BeNil())
+			err = linkBin(path.Join(tempDir, "go.sum"), linkPath)
+			Expect(err).To(BeNil())
+
+			target, err := os.Readlink(linkPath)
+			Expect(err).To(BeNil())
+			Expect(target).To(Equal(path.Join(tempDir, "go.sum")))
+
+			files, err := ioutil.ReadDir(tempDir)
+			Expect(err).To(BeNil())
+			for _, file := range files {
+				Expect(file.Name()).ToNot(HaveSuffix(".tmp"))
+			}
+		})
+	})
+
+	Context("Exec", func() {
+		It("should run the binary with the bin directory on PATH", func() {
+			options := &Options{PkgRoot: tempDir, Env: []string{"PATH=/usr/bin"}}
+			exitCode, err := Exec("hello-world", []string{"1"}, options)
+			Expect(err).To(BeNil())
+			Expect(exitCode).To(Equal(1))
+			Expect(options.Env).To(Equal([]string{"PATH=/usr/bin"}))
+		})
+	})
+})
@@ -0,0 +1,47 @@
+package gomodrun
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"runtime"
+
+	"github.com/otiai10/copy"
+)
+
+// createProjectFixture copies the alternative-tools-dir test project, which has hello-world in its tools file, to a
+// temp directory with an empty cache.
+func createProjectFixture() string {
+	tempDir, err := ioutil.TempDir(os.TempDir(), "gomodrun-project")
+	if err != nil {
+		panic(err)
+	}
+
+	//nolint:dogsled // Test file, don't need any of the extra values
+	_, filename, _, _ := runtime.Caller(0)
+	err = copy.Copy(path.Join(path.Dir(filename), "./tests/alternative-tools-dir"), tempDir)
+	if err != nil {
+		panic(err)
+	}
+
+	// Other specs build in to the source directory, start from an empty cache.
+	err = os.RemoveAll(path.Join(tempDir, ".gomodrun"))
+	if err != nil {
+		panic(err)
+	}
+
+	return tempDir
+}
+
+// writeFakeBin writes a placeholder binary to binPath, creating its directory.
+func writeFakeBin(binPath string) {
+	err := os.MkdirAll(path.Dir(binPath), 0750)
+	if err != nil {
+		panic(err)
+	}
+
+	err = ioutil.WriteFile(binPath, []byte("bin"), 0600)
+	if err != nil {
+		panic(err)
+	}
+}
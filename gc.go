@@ -0,0 +1,191 @@
+// Package gomodrun is the forgotten go tool that executes and caches binaries included in go.mod files.
+// This makes it easy to version cli tools in your projects such as `golangci-lint`
+// and `ginkgo` that are versioned locked to what you specify in `go.mod`.
+// Binaries are cached by go version and package version.
+package gomodrun
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// GCOptions contains the policies used by GC to evict binaries from a cache. Policies set to zero are disabled.
+type GCOptions struct {
+	CacheDir    string        // Cache directory to collect. Defaults to .gomodrun in the package root.
+	MaxAge      time.Duration // Remove binaries that have not been used within this duration.
+	MaxVersions int           // Keep at most this many builds per tool, counting each go version and module version pair.
+	MaxSize     int64         // Evict least recently used binaries until the cache is at most this many bytes.
+	DryRun      bool          // Report what would be removed without deleting anything.
+}
+
+type cacheEntry struct {
+	path     string
+	tool     string
+	size     int64
+	lastUsed time.Time
+}
+
+// markBinUsed records the last time a cached binary was used by updating its modification time.
+func markBinUsed(binPath string) {
+	now := time.Now()
+	os.Chtimes(binPath, now, now) //nolint // Ignore error, a read only cache is still usable.
+}
+
+// getCacheTool returns the tool a cached binary belongs to with the go version and module version removed.
+func getCacheTool(cacheDir, binPath string) (string, error) {
+	relPath, err := filepath.Rel(cacheDir, binPath)
+	if err != nil {
+		return "", err
+	}
+
+	parts := strings.Split(filepath.ToSlash(relPath), "/")[1:]
+	for idx, part := range parts {
+		if strings.Contains(part, "@") {
+			parts[idx] = strings.Split(part, "@")[0]
+		}
+	}
+
+	return path.Join(parts...), nil
+}
+
+func getCacheEntries(cacheDir string) ([]cacheEntry, error) {
+	cacheFiles, err := ioutil.ReadDir(cacheDir)
+	if err != nil {
+		return nil, err
+	}
+
+	binPaths := []string{}
+	for _, file := range cacheFiles {
+		if !isGoVersionDir(file) {
+			continue
+		}
+
+		versionBins, err := getAllBins(path.Join(cacheDir, file.Name()))
+		if err != nil {
+			return nil, err
+		}
+		binPaths = append(binPaths, versionBins...)
+	}
+
+	entries := []cacheEntry{}
+	for _, binPath := range binPaths {
+		info, err := os.Stat(binPath)
+		if err != nil {
+			return nil, err
+		}
+
+		size, err := getCacheEntrySize(binPath)
+		if err != nil {
+			return nil, err
+		}
+
+		tool, err := getCacheTool(cacheDir, binPath)
+		if err != nil {
+			return nil, err
+		}
+
+		entries = append(entries, cacheEntry{
+			path:     binPath,
+			tool:     tool,
+			size:     size,
+			lastUsed: info.ModTime(),
+		})
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].lastUsed.After(entries[j].lastUsed)
+	})
+
+	return entries, nil
+}
+
+// GC evicts binaries from the cache that fall outside of the provided policies, returning a report of every binary
+// that was kept or removed.
+func GC(pkgRoot string, options *GCOptions) (*TidyReport, error) {
+	if options == nil {
+		options = &GCOptions{}
+	}
+
+	var err error
+	cacheDir := options.CacheDir
+	if cacheDir == "" {
+		if pkgRoot == "" {
+			pkgRoot, err = GetPkgRoot()
+			if err != nil {
+				return nil, err
+			}
+		}
+		cacheDir = path.Join(pkgRoot, ".gomodrun")
+	}
+
+	report := &TidyReport{
+		PkgRoot: path.Dir(cacheDir),
+		DryRun:  options.DryRun,
+		Kept:    []TidyEntry{},
+		Removed: []TidyEntry{},
+	}
+
+	if _, err = os.Stat(cacheDir); os.IsNotExist(err) {
+		return report, nil
+	}
+
+	entries, err := getCacheEntries(cacheDir)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	var keptSize int64
+	toolVersions := map[string]int{}
+	for _, entry := range entries {
+		reason := ""
+		switch {
+		case options.MaxAge > 0 && now.Sub(entry.lastUsed) > options.MaxAge:
+			reason = fmt.Sprintf("not used since %s", entry.lastUsed.Format(time.RFC3339))
+		case options.MaxVersions > 0 && toolVersions[entry.tool] >= options.MaxVersions:
+			reason = fmt.Sprintf("%d more recently used builds of %s are cached", toolVersions[entry.tool], entry.tool)
+		case options.MaxSize > 0 && keptSize+entry.size > options.MaxSize:
+			reason = fmt.Sprintf("cache exceeds %d bytes, least recently used", options.MaxSize)
+		}
+
+		tidyEntry := TidyEntry{
+			Path: entry.path,
+			Size: entry.size,
+		}
+
+		if reason == "" {
+			tidyEntry.Reason = fmt.Sprintf("last used %s", entry.lastUsed.Format(time.RFC3339))
+			report.Kept = append(report.Kept, tidyEntry)
+			toolVersions[entry.tool]++
+			keptSize += entry.size
+			continue
+		}
+
+		tidyEntry.Reason = reason
+		report.Removed = append(report.Removed, tidyEntry)
+
+		if !options.DryRun {
+			err = removeCacheEntry(entry.path)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if options.DryRun || len(report.Removed) == 0 {
+		return report, nil
+	}
+
+	err = cleanEmptyDirectory(cacheDir)
+	if err != nil {
+		return nil, err
+	}
+
+	return report, nil
+}
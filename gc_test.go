@@ -0,0 +1,124 @@
+package gomodrun
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("gc", func() {
+	var cacheDir string
+
+	createBin := func(binPath string, size int, lastUsed time.Time) string {
+		fullPath := path.Join(cacheDir, binPath, path.Base(binPath))
+		err := os.MkdirAll(path.Dir(fullPath), 0750)
+		if err != nil {
+			panic(err)
+		}
+
+		err = ioutil.WriteFile(fullPath, make([]byte, size), 0600)
+		if err != nil {
+			panic(err)
+		}
+
+		err = os.Chtimes(fullPath, lastUsed, lastUsed)
+		if err != nil {
+			panic(err)
+		}
+
+		return fullPath
+	}
+
+	exists := func(filePath string) bool {
+		_, err := os.Stat(filePath)
+		return !os.IsNotExist(err)
+	}
+
+	BeforeEach(func() {
+		var err error
+		cacheDir, err = ioutil.TempDir(os.TempDir(), "gomodrun-gc")
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	AfterEach(func() {
+		err := os.RemoveAll(cacheDir)
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	It("should remove binaries not used within max age", func() {
+		recent := createBin("go1.0.1/github.com/dustinblackman/go-hello-world-test@v0.0.2/hello-world", 10, time.Now().Add(-time.Hour))
+		old := createBin("go1.0.1/github.com/dustinblackman/go-hello-world-test@v0.0.1/hello-world", 10, time.Now().Add(-72*time.Hour))
+
+		report, err := GC("", &GCOptions{CacheDir: cacheDir, MaxAge: 48 * time.Hour})
+		Expect(err).To(BeNil())
+		Expect(report.Removed).To(HaveLen(1))
+		Expect(report.Removed[0].Path).To(Equal(old))
+		Expect(exists(recent)).To(BeTrue())
+		Expect(exists(old)).To(BeFalse())
+		Expect(exists(path.Dir(path.Dir(old)))).To(BeFalse())
+	})
+
+	It("should keep at most max versions per tool", func() {
+		newest := createBin("go1.0.2/github.com/dustinblackman/go-hello-world-test@v0.0.2/hello-world", 10, time.Now().Add(-time.Hour))
+		middle := createBin("go1.0.1/github.com/dustinblackman/go-hello-world-test@v0.0.2/hello-world", 10, time.Now().Add(-2*time.Hour))
+		oldest := createBin("go1.0.1/github.com/dustinblackman/go-hello-world-test@v0.0.1/hello-world", 10, time.Now().Add(-3*time.Hour))
+		other := createBin("go1.0.1/github.com/dustinblackman/other@v0.0.1/other", 10, time.Now().Add(-4*time.Hour))
+
+		report, err := GC("", &GCOptions{CacheDir: cacheDir, MaxVersions: 2})
+		Expect(err).To(BeNil())
+		Expect(report.Removed).To(HaveLen(1))
+		Expect(exists(newest)).To(BeTrue())
+		Expect(exists(middle)).To(BeTrue())
+		Expect(exists(oldest)).To(BeFalse())
+		Expect(exists(other)).To(BeTrue())
+	})
+
+	It("should evict least recently used binaries when over max size", func() {
+		newest := createBin("go1.0.1/github.com/dustinblackman/a@v0.0.1/a", 10, time.Now().Add(-time.Hour))
+		middle := createBin("go1.0.1/github.com/dustinblackman/b@v0.0.1/b", 10, time.Now().Add(-2*time.Hour))
+		oldest := createBin("go1.0.1/github.com/dustinblackman/c@v0.0.1/c", 10, time.Now().Add(-3*time.Hour))
+
+		report, err := GC("", &GCOptions{CacheDir: cacheDir, MaxSize: 25})
+		Expect(err).To(BeNil())
+		Expect(report.ReclaimedBytes()).To(Equal(int64(10)))
+		Expect(exists(newest)).To(BeTrue())
+		Expect(exists(middle)).To(BeTrue())
+		Expect(exists(oldest)).To(BeFalse())
+	})
+
+	It("should keep everything with nil options", func() {
+		bin := createBin(".gomodrun/go1.0.1/github.com/dustinblackman/go-hello-world-test@v0.0.2/hello-world", 10, time.Now().Add(-72*time.Hour))
+
+		report, err := GC(cacheDir, nil)
+		Expect(err).To(BeNil())
+		Expect(report.Kept).To(HaveLen(1))
+		Expect(report.Removed).To(BeEmpty())
+		Expect(exists(bin)).To(BeTrue())
+	})
+
+	It("should not delete anything on a dry run", func() {
+		old := createBin("go1.0.1/github.com/dustinblackman/go-hello-world-test@v0.0.1/hello-world", 10, time.Now().Add(-72*time.Hour))
+
+		report, err := GC("", &GCOptions{CacheDir: cacheDir, MaxAge: time.Hour, DryRun: true})
+		Expect(err).To(BeNil())
+		Expect(report.Removed).To(HaveLen(1))
+		Expect(exists(old)).To(BeTrue())
+	})
+
+	It("should update the last used time of a binary", func() {
+		bin := createBin("go1.0.1/github.com/dustinblackman/go-hello-world-test@v0.0.1/hello-world", 10, time.Now().Add(-72*time.Hour))
+		markBinUsed(bin)
+
+		info, err := os.Stat(bin)
+		Expect(err).To(BeNil())
+		Expect(info.ModTime()).To(BeTemporally("~", time.Now(), time.Minute))
+	})
+})
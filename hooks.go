@@ -0,0 +1,165 @@
+// Package gomodrun is the forgotten go tool that executes and caches binaries included in go.mod files.
+// This makes it easy to version cli tools in your projects such as `golangci-lint`
+// and `ginkgo` that are versioned locked to what you specify in `go.mod`.
+// Binaries are cached by go version and package version.
+package gomodrun
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// HookEvent is a point in building or running a tool where hooks fire.
+type HookEvent string
+
+// Hook events, in the order they fire. Build hooks only fire when the tool isn't cached.
+const (
+	HookPreBuild  HookEvent = "pre-build"
+	HookPostBuild HookEvent = "post-build"
+	HookPreRun    HookEvent = "pre-run"
+	HookPostRun   HookEvent = "post-run"
+)
+
+// HookContext describes the tool a hook fires for.
+type HookContext struct {
+	Event    HookEvent // Event the hook fires for.
+	Tool     string    // Binary name of the tool, without .exe.
+	Version  string    // Module version of the tool.
+	Args     []string  // Arguments passed to the tool. Empty when building without running.
+	BinPath  string    // Path to the cached binary.
+	ExitCode int       // Exit code of the tool, only set for post-run.
+}
+
+// HookFunc is called when a hook fires. Errors from pre hooks stop the build or run.
+type HookFunc func(hook *HookContext) error
+
+// HooksConfig contains shell commands to run for each hook event. Commands run in the package root with the
+// GOMODRUN_HOOK, GOMODRUN_TOOL, GOMODRUN_VERSION, GOMODRUN_ARGS, GOMODRUN_ARG_<n>, GOMODRUN_BIN and
+// GOMODRUN_EXIT_CODE variables set. GOMODRUN_ARGS quotes the arguments for sh, and GOMODRUN_ARG_1 onwards hold each
+// argument as is.
+type HooksConfig struct {
+	PreBuild  []string `yaml:"pre-build"`  // Commands run before the tool is built.
+	PostBuild []string `yaml:"post-build"` // Commands run after the tool is built.
+	PreRun    []string `yaml:"pre-run"`    // Commands run before the tool runs.
+	PostRun   []string `yaml:"post-run"`   // Commands run after the tool exits.
+}
+
+func (h *HooksConfig) getCommands(event HookEvent) []string {
+	switch event {
+	case HookPreBuild:
+		return h.PreBuild
+	case HookPostBuild:
+		return h.PostBuild
+	case HookPreRun:
+		return h.PreRun
+	case HookPostRun:
+		return h.PostRun
+	}
+
+	return nil
+}
+
+// AddHook registers fn to be called when event fires for any tool. Callbacks run in the order they're added, before
+// any hooks from the config.
+func (r *Runner) AddHook(event HookEvent, fn HookFunc) {
+	if r.hooks == nil {
+		r.hooks = map[HookEvent][]HookFunc{}
+	}
+
+	r.hooks[event] = append(r.hooks[event], fn)
+}
+
+// getCmdPathVersion returns the module version in cmdPath, such as v1.0.0 in github.com/org/tool@v1.0.0/cmd/tool.
+func getCmdPathVersion(cmdPath string) string {
+	parts := strings.SplitN(cmdPath, "@", 2)
+	if len(parts) != 2 {
+		return ""
+	}
+
+	return strings.SplitN(parts[1], "/", 2)[0]
+}
+
+// shellQuote quotes arg for sh unless it only contains characters that don't need quoting.
+func shellQuote(arg string) string {
+	if arg != "" && strings.Trim(arg, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789@%+=:,./_-") == "" {
+		return arg
+	}
+
+	return "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+}
+
+// getHookEnv returns the variables describing hook to its commands.
+func getHookEnv(hook *HookContext) []string {
+	quotedArgs := []string{}
+	argsEnv := []string{}
+	for idx, arg := range hook.Args {
+		quotedArgs = append(quotedArgs, shellQuote(arg))
+		argsEnv = append(argsEnv, "GOMODRUN_ARG_"+strconv.Itoa(idx+1)+"="+arg)
+	}
+
+	return append([]string{
+		"GOMODRUN_HOOK=" + string(hook.Event),
+		"GOMODRUN_TOOL=" + hook.Tool,
+		"GOMODRUN_VERSION=" + hook.Version,
+		"GOMODRUN_ARGS=" + strings.Join(quotedArgs, " "),
+		"GOMODRUN_BIN=" + hook.BinPath,
+		"GOMODRUN_EXIT_CODE=" + strconv.Itoa(hook.ExitCode),
+	}, argsEnv...)
+}
+
+// runHooks fires the callbacks and config commands for hook.Event, stopping at the first error.
+func (r *Runner) runHooks(hook *HookContext) error {
+	for _, fn := range r.hooks[hook.Event] {
+		err := fn(hook)
+		if err != nil {
+			return fmt.Errorf("%s hook for %s failed: %s", hook.Event, hook.Tool, err)
+		}
+	}
+
+	config, err := r.loadConfig()
+	if err != nil {
+		return err
+	}
+
+	toolHooks := config.Tools[hook.Tool].Hooks
+	commands := append(append([]string{}, config.Hooks.getCommands(hook.Event)...), toolHooks.getCommands(hook.Event)...)
+	for _, command := range commands {
+		start := time.Now()
+		err = r.runHookCommand(command, hook)
+		if err != nil {
+			return fmt.Errorf("%s hook for %s failed: %s: %s", hook.Event, hook.Tool, command, err)
+		}
+		logTiming(r.Logger, start, "ran %s hook %s", hook.Event, command)
+	}
+
+	return nil
+}
+
+func (r *Runner) runHookCommand(command string, hook *HookContext) error {
+	env, err := r.getToolEnv(hook.Tool)
+	if err != nil {
+		return err
+	}
+
+	cmd := exec.Command("sh", "-c", command)
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", command)
+	}
+	cmd.Dir = r.PkgRoot
+	cmd.Stdout = r.Stdout
+	cmd.Stderr = r.Stderr
+	if cmd.Stdout == nil {
+		cmd.Stdout = ioutil.Discard
+	}
+	if cmd.Stderr == nil {
+		cmd.Stderr = ioutil.Discard
+	}
+	cmd.Env = mergeEnv(env, getHookEnv(hook))
+
+	return cmd.Run()
+}
@@ -0,0 +1,142 @@
+package gomodrun
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("hooks", func() {
+	var tempDir string
+	var toolchain *fakeToolchain
+	var runner *Runner
+
+	BeforeEach(func() {
+		tempDir = createProjectFixture()
+		srcDir := path.Join(tempDir, "src")
+		err := os.MkdirAll(srcDir, 0750)
+		Expect(err).To(BeNil())
+		err = ioutil.WriteFile(path.Join(srcDir, "go.mod"), []byte("module example.com/hello-world\n"), 0600)
+		Expect(err).To(BeNil())
+
+		toolchain = &fakeToolchain{}
+		runner, err = NewRunner(&Options{PkgRoot: tempDir})
+		Expect(err).To(BeNil())
+		runner.Toolchain = toolchain
+		runner.Sources = &fakeSources{dir: srcDir}
+		runner.Cache = &fakeCache{dir: path.Join(tempDir, "cache")}
+	})
+
+	AfterEach(func() {
+		err := os.RemoveAll(tempDir)
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	It("should call hooks around building and running a tool", func() {
+		hooks := []HookContext{}
+		for _, event := range []HookEvent{HookPreBuild, HookPostBuild, HookPreRun, HookPostRun} {
+			runner.AddHook(event, func(hook *HookContext) error {
+				hooks = append(hooks, *hook)
+				return nil
+			})
+		}
+
+		exitCode, err := runner.Run("hello-world", []string{"a"})
+		Expect(err).To(BeNil())
+		Expect(exitCode).To(Equal(3))
+
+		binPath := path.Join(tempDir, "cache", "go9.9.9", "hello-world")
+		Expect(hooks).To(Equal([]HookContext{
+			{Event: HookPreBuild, Tool: "hello-world", Version: "v0.0.2", Args: []string{"a"}, BinPath: binPath},
+			{Event: HookPostBuild, Tool: "hello-world", Version: "v0.0.2", Args: []string{"a"}, BinPath: binPath},
+			{Event: HookPreRun, Tool: "hello-world", Version: "v0.0.2", Args: []string{"a"}, BinPath: binPath},
+			{Event: HookPostRun, Tool: "hello-world", Version: "v0.0.2", Args: []string{"a"}, BinPath: binPath, ExitCode: 3},
+		}))
+
+		hooks = []HookContext{}
+		_, err = runner.Run("hello-world", []string{})
+		Expect(err).To(BeNil())
+		Expect(hooks).To(HaveLen(2))
+		Expect(hooks[0].Event).To(Equal(HookPreRun))
+		Expect(hooks[1].Event).To(Equal(HookPostRun))
+	})
+
+	It("should not run the tool when a pre-run hook fails", func() {
+		runner.AddHook(HookPreRun, func(hook *HookContext) error {
+			return errors.New("generated files are stale")
+		})
+
+		exitCode, err := runner.Run("hello-world", []string{})
+		Expect(err).To(MatchError("pre-run hook for hello-world failed: generated files are stale"))
+		Expect(exitCode).To(Equal(-1))
+	})
+
+	It("should run hook commands from the config", func() {
+		config := `hooks:
+  post-run:
+    - echo "all $GOMODRUN_HOOK $GOMODRUN_TOOL $GOMODRUN_VERSION $GOMODRUN_ARGS $GOMODRUN_EXIT_CODE" >> hooks.txt
+tools:
+  hello-world:
+    hooks:
+      pre-build:
+        - echo "tool $GOMODRUN_HOOK" >> hooks.txt
+      post-run:
+        - echo "tool $GOMODRUN_HOOK" >> hooks.txt
+`
+		err := ioutil.WriteFile(path.Join(tempDir, ConfigFileName), []byte(config), 0600)
+		Expect(err).To(BeNil())
+
+		_, err = runner.Run("hello-world", []string{"a", "b"})
+		Expect(err).To(BeNil())
+
+		data, err := ioutil.ReadFile(path.Join(tempDir, "hooks.txt"))
+		Expect(err).To(BeNil())
+		Expect(string(data)).To(Equal("tool pre-build\nall post-run hello-world v0.0.2 a b 3\ntool post-run\n"))
+	})
+
+	It("should pass arguments to hook commands without splitting them", func() {
+		config := `hooks:
+  pre-run:
+    - eval "set -- $GOMODRUN_ARGS" && printf '%s|' "$#" "$@" "$GOMODRUN_ARG_2" > hooks.txt
+`
+		err := ioutil.WriteFile(path.Join(tempDir, ConfigFileName), []byte(config), 0600)
+		Expect(err).To(BeNil())
+
+		_, err = runner.Run("hello-world", []string{"a b", "it's", "", "$HOME"})
+		Expect(err).To(BeNil())
+
+		data, err := ioutil.ReadFile(path.Join(tempDir, "hooks.txt"))
+		Expect(err).To(BeNil())
+		Expect(string(data)).To(Equal("4|a b|it's||$HOME|it's|"))
+	})
+
+	It("should load the config once per runner", func() {
+		err := ioutil.WriteFile(path.Join(tempDir, ConfigFileName), []byte("hooks:\n  pre-run:\n    - echo run >> hooks.txt\n"), 0600)
+		Expect(err).To(BeNil())
+
+		config, err := runner.loadConfig()
+		Expect(err).To(BeNil())
+		err = os.Remove(path.Join(tempDir, ConfigFileName))
+		Expect(err).To(BeNil())
+
+		loaded, err := runner.loadConfig()
+		Expect(err).To(BeNil())
+		Expect(loaded).To(BeIdenticalTo(config))
+		Expect(loaded.Hooks.PreRun).To(Equal([]string{"echo run >> hooks.txt"}))
+	})
+
+	It("should return an error when a hook command fails", func() {
+		err := ioutil.WriteFile(path.Join(tempDir, ConfigFileName), []byte("hooks:\n  pre-build:\n    - exit 1\n"), 0600)
+		Expect(err).To(BeNil())
+
+		_, err = runner.Build("hello-world")
+		Expect(err).To(MatchError(ContainSubstring("pre-build hook for hello-world failed: exit 1")))
+		Expect(toolchain.calls).To(BeEmpty())
+	})
+})
@@ -0,0 +1,230 @@
+// Package gomodrun is the forgotten go tool that executes and caches binaries included in go.mod files.
+// This makes it easy to version cli tools in your projects such as `golangci-lint`
+// and `ginkgo` that are versioned locked to what you specify in `go.mod`.
+// Binaries are cached by go version and package version.
+package gomodrun
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// indexFileName is the resolution index in .gomodrun. It's a file so it's never mistaken for a go version directory.
+const indexFileName = "index.json"
+
+// indexLockTimeout is how long saving to the index waits for another process to release the index lock.
+const indexLockTimeout = 5 * time.Second
+
+// indexLockStale is the age after which an index lock is treated as left behind by a killed process.
+const indexLockStale = 30 * time.Second
+
+// indexEnv are environment variables that change what go version is used or where sources are found.
+var indexEnv = []string{"GOTOOLCHAIN", "GOROOT", "GOPATH", "GOFLAGS"}
+
+// resolutionIndex stores resolutions for a single set of inputs, identified by Key.
+type resolutionIndex struct {
+	Key   string                 `json:"key"`
+	Tools map[string]*Resolution `json:"tools"`
+}
+
+// useIndex returns true when resolutions can be stored in the index, which only covers the default toolchain,
+// sources and cache.
+func (r *Runner) useIndex() bool {
+	return !r.NoIndex && isDefaultToolchain(r.Toolchain) && r.Sources == nil && r.Cache == nil
+}
+
+// isDefaultToolchain returns true when toolchain is unset, or is RunBatch's cachedToolchain wrapping the default
+// toolchain.
+func isDefaultToolchain(toolchain Toolchain) bool {
+	if toolchain == nil {
+		return true
+	}
+
+	if cached, ok := toolchain.(*cachedToolchain); ok {
+		_, ok = cached.Toolchain.(*goToolchain)
+		return ok
+	}
+
+	return false
+}
+
+func (r *Runner) getIndexPath() string {
+	return path.Join(r.cacheDir(), indexFileName)
+}
+
+// getEnvValue returns the value of key in env, or the current environment when env is nil.
+func getEnvValue(env []string, key string) string {
+	if env == nil {
+		return os.Getenv(key)
+	}
+
+	value := ""
+	for _, entry := range env {
+		if getEnvKey(entry) == getEnvKey(key) {
+			value = strings.SplitN(entry, "=", 2)[1]
+		}
+	}
+
+	return value
+}
+
+// getIndexKey returns a hash of the size and modification time of the go binary, go.mod, go.sum, the config and
+// the go files in the package root, along with the environment variables that change how tools resolve.
+func (r *Runner) getIndexKey() (string, error) {
+	goBin := r.GoBin
+	if goBin == "" {
+		goBin = "go"
+	}
+
+	goPath, err := exec.LookPath(goBin)
+	if err != nil {
+		return "", err
+	}
+
+	filePaths := []string{
+		goPath,
+		path.Join(r.PkgRoot, "go.mod"),
+		path.Join(r.PkgRoot, "go.sum"),
+		path.Join(r.PkgRoot, ConfigFileName),
+	}
+
+	files, err := ioutil.ReadDir(r.PkgRoot)
+	if err != nil {
+		return "", err
+	}
+
+	for _, file := range files {
+		if !file.IsDir() && strings.HasSuffix(file.Name(), ".go") {
+			filePaths = append(filePaths, path.Join(r.PkgRoot, file.Name()))
+		}
+	}
+
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%s %s\n", runtime.GOOS, r.cacheDir())
+	for _, filePath := range filePaths {
+		info, err := os.Stat(filePath)
+		if os.IsNotExist(err) {
+			fmt.Fprintf(hash, "%s missing\n", filePath)
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+
+		fmt.Fprintf(hash, "%s %d %d\n", filePath, info.Size(), info.ModTime().UnixNano())
+	}
+
+	for _, key := range indexEnv {
+		fmt.Fprintf(hash, "%s=%s\n", key, getEnvValue(r.GoEnv, key))
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// readIndex returns the index in the cache directory, or an empty index when it's missing or unreadable.
+func (r *Runner) readIndex() *resolutionIndex {
+	index := &resolutionIndex{}
+	data, err := ioutil.ReadFile(r.getIndexPath())
+	if err == nil {
+		err = json.Unmarshal(data, index)
+	}
+
+	if err != nil || index.Tools == nil {
+		return &resolutionIndex{Tools: map[string]*Resolution{}}
+	}
+
+	return index
+}
+
+// getIndexedResolution returns the resolution stored for binName with key, or nil if it isn't stored.
+func (r *Runner) getIndexedResolution(key, binName string) *Resolution {
+	index := r.readIndex()
+	if index.Key != key {
+		return nil
+	}
+
+	return index.Tools[binName]
+}
+
+// lockIndex creates the index lock file next to the index, waiting while another process holds it. Returns a function
+// that releases the lock.
+func (r *Runner) lockIndex() (func(), error) {
+	lockPath := r.getIndexPath() + ".lock"
+	deadline := time.Now().Add(indexLockTimeout)
+	for {
+		lockFile, err := os.OpenFile(lockPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+		if err == nil {
+			lockFile.Close()
+			return func() {
+				os.Remove(lockPath) //nolint // Ignore error, a stale lock is taken over by the next save.
+			}, nil
+		}
+
+		if !os.IsExist(err) {
+			return nil, err
+		}
+
+		if info, err := os.Stat(lockPath); err == nil && time.Since(info.ModTime()) > indexLockStale {
+			os.Remove(lockPath) //nolint // Ignore error, another process may have taken over the lock first.
+			continue
+		}
+
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("timed out waiting for %s", lockPath)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// saveIndexedResolution stores resolution under key, dropping resolutions stored with any other key. The index is
+// merged under the index lock and replaced with a rename, so concurrent runs don't drop each other's resolutions or
+// read a partly written index.
+func (r *Runner) saveIndexedResolution(key, binName string, resolution *Resolution) error {
+	err := os.MkdirAll(r.cacheDir(), os.ModePerm)
+	if err != nil {
+		return err
+	}
+
+	unlock, err := r.lockIndex()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	index := r.readIndex()
+	if index.Key != key {
+		index = &resolutionIndex{
+			Key:   key,
+			Tools: map[string]*Resolution{},
+		}
+	}
+	index.Tools[binName] = resolution
+
+	data, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tempFile, err := ioutil.TempFile(r.cacheDir(), indexFileName)
+	if err != nil {
+		return err
+	}
+
+	_, err = tempFile.Write(data)
+	tempFile.Close()
+	if err != nil {
+		os.Remove(tempFile.Name()) //nolint // Ignore error, the write already failed.
+		return err
+	}
+
+	return os.Rename(tempFile.Name(), r.getIndexPath())
+}
@@ -0,0 +1,132 @@
+package gomodrun
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("index", func() {
+	var tempDir string
+	var indexPath string
+
+	BeforeEach(func() {
+		tempDir = createProjectFixture()
+		indexPath = path.Join(tempDir, ".gomodrun", indexFileName)
+	})
+
+	AfterEach(func() {
+		err := os.RemoveAll(tempDir)
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	resolve := func(runner *Runner) (*Resolution, []string) {
+		logger := &recordingLogger{}
+		runner.Logger = logger
+		resolution, err := runner.Resolve("hello-world")
+		Expect(err).To(BeNil())
+
+		return resolution, logger.messages
+	}
+
+	It("should resolve warm runs from the index", func() {
+		runner := &Runner{Options: Options{PkgRoot: tempDir}}
+		cold, messages := resolve(runner)
+		Expect(messages[0]).To(HavePrefix("parsed tools package"))
+
+		_, err := os.Stat(indexPath)
+		Expect(err).To(BeNil())
+
+		warm, messages := resolve(runner)
+		Expect(warm).To(Equal(cold))
+		Expect(messages).To(HaveLen(1))
+		Expect(messages[0]).To(HavePrefix("found hello-world in " + indexPath + " in "))
+	})
+
+	It("should resolve again when go.mod changes", func() {
+		runner := &Runner{Options: Options{PkgRoot: tempDir}}
+		resolve(runner)
+
+		later := time.Now().Add(time.Minute)
+		err := os.Chtimes(path.Join(tempDir, "go.mod"), later, later)
+		Expect(err).To(BeNil())
+
+		_, messages := resolve(runner)
+		Expect(messages[0]).To(HavePrefix("parsed tools package"))
+
+		_, messages = resolve(runner)
+		Expect(messages[0]).To(HavePrefix("found hello-world in "))
+	})
+
+	It("should ignore a corrupted index", func() {
+		err := os.MkdirAll(path.Dir(indexPath), 0750)
+		Expect(err).To(BeNil())
+		err = ioutil.WriteFile(indexPath, []byte("{"), 0600)
+		Expect(err).To(BeNil())
+
+		resolution, _ := resolve(&Runner{Options: Options{PkgRoot: tempDir}})
+		Expect(resolution.CmdPath).To(Equal("github.com/dustinblackman/go-hello-world-test@v0.0.2/hello-world"))
+	})
+
+	It("should use the index when running a batch", func() {
+		runner := &Runner{Options: Options{PkgRoot: tempDir, Stdout: ioutil.Discard}}
+		results := runner.RunBatch([]Invocation{{BinName: "hello-world"}}, 1)
+		Expect(results[0].Err).To(BeNil())
+
+		_, err := os.Stat(indexPath)
+		Expect(err).To(BeNil())
+
+		_, messages := resolve(runner)
+		Expect(messages[0]).To(HavePrefix("found hello-world in "))
+	})
+
+	It("should keep resolutions saved concurrently", func() {
+		runner := &Runner{Options: Options{PkgRoot: tempDir}}
+		wg := sync.WaitGroup{}
+		for idx := 0; idx < 10; idx++ {
+			wg.Add(1)
+			go func(idx int) {
+				defer GinkgoRecover()
+				defer wg.Done()
+				err := runner.saveIndexedResolution("key", fmt.Sprintf("tool-%d", idx), &Resolution{})
+				Expect(err).To(BeNil())
+			}(idx)
+		}
+		wg.Wait()
+
+		Expect(runner.readIndex().Tools).To(HaveLen(10))
+		_, err := os.Stat(indexPath + ".lock")
+		Expect(os.IsNotExist(err)).To(BeTrue())
+	})
+
+	It("should take over a stale index lock", func() {
+		err := os.MkdirAll(path.Dir(indexPath), 0750)
+		Expect(err).To(BeNil())
+		err = ioutil.WriteFile(indexPath+".lock", []byte{}, 0600)
+		Expect(err).To(BeNil())
+		stale := time.Now().Add(-2 * indexLockStale)
+		err = os.Chtimes(indexPath+".lock", stale, stale)
+		Expect(err).To(BeNil())
+
+		runner := &Runner{Options: Options{PkgRoot: tempDir}}
+		err = runner.saveIndexedResolution("key", "hello-world", &Resolution{})
+		Expect(err).To(BeNil())
+		Expect(runner.getIndexedResolution("key", "hello-world")).ToNot(BeNil())
+	})
+
+	It("should not use the index when disabled or with a custom toolchain", func() {
+		resolve(&Runner{Options: Options{PkgRoot: tempDir}, NoIndex: true})
+		resolve(&Runner{Options: Options{PkgRoot: tempDir}, Toolchain: &fakeToolchain{}})
+
+		_, err := os.Stat(indexPath)
+		Expect(os.IsNotExist(err)).To(BeTrue())
+	})
+})
@@ -0,0 +1,179 @@
+// Package gomodrun is the forgotten go tool that executes and caches binaries included in go.mod files.
+// This makes it easy to version cli tools in your projects such as `golangci-lint`
+// and `ginkgo` that are versioned locked to what you specify in `go.mod`.
+// Binaries are cached by go version and package version.
+package gomodrun
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"sort"
+	"strings"
+)
+
+// Presets are named sets of tools Init can populate the tools file with.
+var Presets = map[string][]string{
+	"lint":    {"github.com/golangci/golangci-lint/cmd/golangci-lint", "mvdan.cc/gofumpt"},
+	"test":    {"github.com/onsi/ginkgo/v2/ginkgo", "gotest.tools/gotestsum"},
+	"mock":    {"github.com/golang/mock/mockgen"},
+	"proto":   {"google.golang.org/protobuf/cmd/protoc-gen-go", "google.golang.org/grpc/cmd/protoc-gen-go-grpc"},
+	"release": {"github.com/goreleaser/goreleaser"},
+}
+
+// InitOptions contains parameters that change how Init scaffolds a project.
+type InitOptions struct {
+	Preset string // Name of a preset in Presets to populate the tools file with.
+}
+
+// addGitIgnore adds .gomodrun to the .gitignore in pkgRoot, returning true if the file was changed.
+func addGitIgnore(pkgRoot string) (bool, error) {
+	gitIgnorePath := path.Join(pkgRoot, ".gitignore")
+	data, err := ioutil.ReadFile(gitIgnorePath)
+	if err != nil && !os.IsNotExist(err) {
+		return false, err
+	}
+
+	for _, line := range strings.Split(string(data), "\n") {
+		if strings.Trim(strings.TrimSpace(line), "/") == ".gomodrun" {
+			return false, nil
+		}
+	}
+
+	if len(data) > 0 && !strings.HasSuffix(string(data), "\n") {
+		data = append(data, '\n')
+	}
+	data = append(data, []byte("/.gomodrun/\n")...)
+
+	return true, ioutil.WriteFile(gitIgnorePath, data, 0o644) //nolint:gosec // .gitignore is world readable.
+}
+
+// Init scaffolds gomodrun in a project by creating a tools file with the tools build constraint, adding .gomodrun to
+// .gitignore, and writing a starter .gomodrun.yml. Existing files are left in place. When a preset is provided, its
+// tools are added to the tools file and go.mod. Returns the paths of the files that were created or changed.
+func Init(pkgRoot string, options *InitOptions) ([]string, error) {
+	var err error
+	if pkgRoot == "" {
+		pkgRoot, err = GetPkgRoot()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if options == nil {
+		options = &InitOptions{}
+	}
+
+	var presetPkgs []string
+	if options.Preset != "" {
+		var ok bool
+		presetPkgs, ok = Presets[options.Preset]
+		if !ok {
+			presetNames := []string{}
+			for name := range Presets {
+				presetNames = append(presetNames, name)
+			}
+			sort.Strings(presetNames)
+			return nil, fmt.Errorf("unknown preset %s, expected one of %s", options.Preset, strings.Join(presetNames, ", "))
+		}
+	}
+
+	// Everything is checked before the first write so a failing Init doesn't leave the project partly scaffolded.
+	configPath := path.Join(pkgRoot, ConfigFileName)
+	_, err = os.Stat(configPath)
+	configCreated := os.IsNotExist(err)
+
+	toolsFile, err := getToolsFile(pkgRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	var toolsSrc []byte
+	toolsFileCreated := toolsFile == ""
+	if toolsFileCreated {
+		toolsFile = path.Join(pkgRoot, "tools.go")
+		if _, err = os.Stat(toolsFile); !os.IsNotExist(err) {
+			return nil, fmt.Errorf("%s exists but is not a tools file", toolsFile)
+		}
+
+		pkgName, err := getToolsPkgName(pkgRoot)
+		if err != nil {
+			return nil, err
+		}
+
+		// The starter config uses the default tags, so they match the config once it's written.
+		config, err := LoadConfig(pkgRoot)
+		if err != nil {
+			return nil, err
+		}
+
+		toolsSrc, err = newToolsFile(pkgName, config.Tags, []string{})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	gitIgnorePath := path.Join(pkgRoot, ".gitignore")
+	snapshot, err := getModSnapshot(pkgRoot, configPath, toolsFile, gitIgnorePath)
+	if err != nil {
+		return nil, err
+	}
+
+	changed, err := initFiles(pkgRoot, configPath, configCreated, toolsFile, toolsSrc, presetPkgs)
+	if err != nil {
+		// Restore every file Init touched so a failing preset doesn't leave earlier tools added.
+		snapshot.restore()
+		return nil, err
+	}
+
+	return changed, nil
+}
+
+// initFiles writes the files scaffolded by Init and adds presetPkgs, returning the paths of the files that were
+// created or changed. toolsSrc is written to toolsFile when it's not nil.
+func initFiles(
+	pkgRoot, configPath string, configCreated bool, toolsFile string, toolsSrc []byte, presetPkgs []string,
+) ([]string, error) {
+	changed := []string{}
+
+	if configCreated {
+		err := ioutil.WriteFile(configPath, []byte(starterConfig), 0o644) //nolint:gosec // Config is world readable.
+		if err != nil {
+			return nil, err
+		}
+		changed = append(changed, configPath)
+	}
+
+	if toolsSrc != nil {
+		err := ioutil.WriteFile(toolsFile, toolsSrc, 0o644) //nolint:gosec // Source files are world readable.
+		if err != nil {
+			return nil, err
+		}
+		changed = append(changed, toolsFile)
+	}
+
+	gitIgnoreChanged, err := addGitIgnore(pkgRoot)
+	if err != nil {
+		return nil, err
+	}
+	if gitIgnoreChanged {
+		changed = append(changed, path.Join(pkgRoot, ".gitignore"))
+	}
+
+	for _, pkg := range presetPkgs {
+		_, err = Add(pkgRoot, pkg, &AddOptions{})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if len(presetPkgs) > 0 {
+		if toolsSrc == nil {
+			changed = append(changed, toolsFile)
+		}
+		changed = append(changed, path.Join(pkgRoot, "go.mod"), path.Join(pkgRoot, "go.sum"))
+	}
+
+	return changed, nil
+}
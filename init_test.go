@@ -0,0 +1,159 @@
+package gomodrun
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("init", func() {
+	var tempDir string
+
+	BeforeEach(func() {
+		var err error
+		tempDir, err = ioutil.TempDir(os.TempDir(), "gomodrun-init")
+		if err != nil {
+			panic(err)
+		}
+
+		tempDir = path.Join(tempDir, "my-app")
+		err = os.MkdirAll(tempDir, 0750)
+		if err != nil {
+			panic(err)
+		}
+
+		err = ioutil.WriteFile(path.Join(tempDir, "go.mod"), []byte("module github.com/dustinblackman/gomodrun-test\n\ngo 1.13\n"), 0600)
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	AfterEach(func() {
+		err := os.RemoveAll(path.Dir(tempDir))
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	It("should create the tools file, config and .gitignore", func() {
+		changed, err := Init(tempDir, &InitOptions{})
+		Expect(err).To(BeNil())
+		Expect(changed).To(Equal([]string{
+			path.Join(tempDir, ConfigFileName),
+			path.Join(tempDir, "tools.go"),
+			path.Join(tempDir, ".gitignore"),
+		}))
+
+		content, err := ioutil.ReadFile(path.Join(tempDir, "tools.go"))
+		Expect(err).To(BeNil())
+		Expect(string(content)).To(Equal("//go:build tools\n// +build tools\n\npackage myapp\n"))
+
+		content, err = ioutil.ReadFile(path.Join(tempDir, ".gitignore"))
+		Expect(err).To(BeNil())
+		Expect(string(content)).To(Equal("/.gomodrun/\n"))
+
+		config, err := LoadConfig(tempDir)
+		Expect(err).To(BeNil())
+		Expect(config.Tags).To(Equal([]string{"tools"}))
+
+		toolsFile, err := getToolsFile(tempDir)
+		Expect(err).To(BeNil())
+		Expect(toolsFile).To(Equal(path.Join(tempDir, "tools.go")))
+	})
+
+	It("should leave existing files in place", func() {
+		err := ioutil.WriteFile(path.Join(tempDir, ".gitignore"), []byte("dist\n.gomodrun/"), 0600)
+		Expect(err).To(BeNil())
+		err = ioutil.WriteFile(path.Join(tempDir, "main.go"), []byte("package main\n"), 0600)
+		Expect(err).To(BeNil())
+
+		_, err = Init(tempDir, &InitOptions{})
+		Expect(err).To(BeNil())
+
+		changed, err := Init(tempDir, &InitOptions{})
+		Expect(err).To(BeNil())
+		Expect(changed).To(BeEmpty())
+
+		content, err := ioutil.ReadFile(path.Join(tempDir, "tools.go"))
+		Expect(err).To(BeNil())
+		Expect(string(content)).To(ContainSubstring("package main\n"))
+
+		content, err = ioutil.ReadFile(path.Join(tempDir, ".gitignore"))
+		Expect(err).To(BeNil())
+		Expect(string(content)).To(Equal("dist\n.gomodrun/"))
+	})
+
+	It("should add the tools from a preset", func() {
+		goProxy := os.Getenv("GOPROXY")
+		// go-hello-world-test is already in the module cache as it's required by gomodrun's go.mod.
+		err := os.Setenv("GOPROXY", "off")
+		Expect(err).To(BeNil())
+		defer os.Setenv("GOPROXY", goProxy) //nolint // Test cleanup
+
+		Presets["hello"] = []string{"github.com/dustinblackman/go-hello-world-test/hello-world@v0.0.2"}
+		defer delete(Presets, "hello")
+
+		_, err = Init(tempDir, &InitOptions{Preset: "hello"})
+		Expect(err).To(BeNil())
+
+		binNames, err := GetToolBinNames(tempDir)
+		Expect(err).To(BeNil())
+		Expect(binNames).To(Equal([]string{"hello-world"}))
+	})
+
+	It("should treat nil options as defaults", func() {
+		changed, err := Init(tempDir, nil)
+		Expect(err).To(BeNil())
+		Expect(changed).To(HaveLen(3))
+	})
+
+	It("should not write anything when tools.go exists but is not a tools file", func() {
+		err := ioutil.WriteFile(path.Join(tempDir, "tools.go"), []byte("package main\n\nfunc main() {}\n"), 0600)
+		Expect(err).To(BeNil())
+
+		_, err = Init(tempDir, &InitOptions{})
+		Expect(err).To(MatchError(ContainSubstring("exists but is not a tools file")))
+
+		for _, fileName := range []string{ConfigFileName, ".gitignore"} {
+			_, err = os.Stat(path.Join(tempDir, fileName))
+			Expect(os.IsNotExist(err)).To(BeTrue())
+		}
+	})
+
+	It("should undo earlier presets and scaffolding when a preset fails", func() {
+		goProxy := os.Getenv("GOPROXY")
+		err := os.Setenv("GOPROXY", "off")
+		Expect(err).To(BeNil())
+		defer os.Setenv("GOPROXY", goProxy) //nolint // Test cleanup
+
+		Presets["hello"] = []string{
+			"github.com/dustinblackman/go-hello-world-test/hello-world@v0.0.2",
+			"example.invalid/not-real/cmd/not-real",
+		}
+		defer delete(Presets, "hello")
+
+		goMod, err := ioutil.ReadFile(path.Join(tempDir, "go.mod"))
+		Expect(err).To(BeNil())
+
+		_, err = Init(tempDir, &InitOptions{Preset: "hello"})
+		Expect(err).ToNot(BeNil())
+
+		content, err := ioutil.ReadFile(path.Join(tempDir, "go.mod"))
+		Expect(err).To(BeNil())
+		Expect(string(content)).To(Equal(string(goMod)))
+
+		for _, fileName := range []string{ConfigFileName, "tools.go", ".gitignore", "go.sum"} {
+			_, err = os.Stat(path.Join(tempDir, fileName))
+			Expect(os.IsNotExist(err)).To(BeTrue())
+		}
+	})
+
+	It("should return an error for unknown presets", func() {
+		changed, err := Init(tempDir, &InitOptions{Preset: "not-real"})
+		Expect(err).ToNot(BeNil())
+		Expect(changed).To(BeNil())
+	})
+})
@@ -0,0 +1,326 @@
+// Package gomodrun is the forgotten go tool that executes and caches binaries included in go.mod files.
+// This makes it easy to version cli tools in your projects such as `golangci-lint`
+// and `ginkgo` that are versioned locked to what you specify in `go.mod`.
+// Binaries are cached by go version and package version.
+package gomodrun
+
+import (
+	"crypto/sha256"
+	"debug/buildinfo"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"sort"
+	"strings"
+)
+
+// LockFileName is the name of the lockfile in the package root.
+const LockFileName = "gomodrun.lock"
+
+// Lockfile records how every tool in the tools file was built.
+type Lockfile struct {
+	Tools []LockedTool `json:"tools"`
+}
+
+// LockedTool records the inputs and output of building a single tool.
+type LockedTool struct {
+	Name       string            `json:"name"`          // Binary name of the tool, without .exe.
+	ImportPath string            `json:"importPath"`    // Import path in the tools file.
+	Module     string            `json:"module"`        // Module path required in go.mod.
+	Version    string            `json:"version"`       // Module version required in go.mod.
+	Sum        string            `json:"sum,omitempty"` // Module hash from go.sum.
+	GoVersion  string            `json:"goVersion"`     // Go version the binary was built with.
+	Settings   map[string]string `json:"settings"`      // Build settings from the binary, such as GOOS, GOARCH and CGO_ENABLED.
+	Deps       []string          `json:"deps"`          // Every module linked in to the binary as `path version sum`.
+	BinaryHash string            `json:"binaryHash"`    // SHA-256 of the binary, such as sha256:abc.
+}
+
+// LockMismatch is a difference between gomodrun.lock and how a tool builds now.
+type LockMismatch struct {
+	Tool    string // Binary name of the tool.
+	Field   string // Name of the field that differs, such as goVersion.
+	Locked  string // Value in gomodrun.lock.
+	Current string // Value of the current build.
+}
+
+// String describes the mismatch for humans.
+func (m *LockMismatch) String() string {
+	return fmt.Sprintf("%s: %s is %s in %s but %s", m.Tool, m.Field, m.Locked, LockFileName, m.Current)
+}
+
+// VerifyOptions contains parameters that change how Verify checks gomodrun.lock.
+type VerifyOptions struct {
+	Rebuild bool // Rebuild every tool in a temporary cache instead of using cached binaries.
+}
+
+func getFileHash(filePath string) (string, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	_, err = io.Copy(hash, file)
+	if err != nil {
+		return "", err
+	}
+
+	return "sha256:" + hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// lockTool builds binName if it isn't cached and records how it was built.
+func (r *Runner) lockTool(binName string) (*LockedTool, error) {
+	resolution, err := r.resolve(binName, false)
+	if err != nil {
+		return nil, err
+	}
+
+	if resolution.Override != "" {
+		return nil, fmt.Errorf("%s is overridden with %s, remove the override to lock it", binName, resolution.Override)
+	}
+
+	cachedBin, err := r.ensureBin(resolution, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := buildinfo.ReadFile(cachedBin)
+	if err != nil {
+		return nil, fmt.Errorf("reading build info of %s failed: %s", cachedBin, err)
+	}
+
+	binaryHash, err := getFileHash(cachedBin)
+	if err != nil {
+		return nil, err
+	}
+
+	tool := &LockedTool{
+		Name:       strings.TrimSuffix(resolution.BinName, ".exe"),
+		ImportPath: resolution.ImportPath,
+		Module:     resolution.Module,
+		Version:    resolution.Version,
+		Sum:        resolution.Sum,
+		GoVersion:  info.GoVersion,
+		Settings:   map[string]string{},
+		Deps:       []string{},
+		BinaryHash: binaryHash,
+	}
+
+	for _, setting := range info.Settings {
+		tool.Settings[setting.Key] = setting.Value
+	}
+
+	for _, dep := range info.Deps {
+		if dep.Replace != nil {
+			dep = dep.Replace
+		}
+		tool.Deps = append(tool.Deps, strings.TrimSpace(strings.Join([]string{dep.Path, dep.Version, dep.Sum}, " ")))
+	}
+
+	return tool, nil
+}
+
+// getLockfile builds every tool in the tools file that isn't cached and records how they were built.
+func (r *Runner) getLockfile() (*Lockfile, error) {
+	binNames, err := r.List()
+	if err != nil {
+		return nil, err
+	}
+
+	lock := &Lockfile{
+		Tools: []LockedTool{},
+	}
+	for _, binName := range binNames {
+		tool, err := r.lockTool(binName)
+		if err != nil {
+			return nil, err
+		}
+		lock.Tools = append(lock.Tools, *tool)
+	}
+
+	sort.SliceStable(lock.Tools, func(i, j int) bool {
+		return lock.Tools[i].Name < lock.Tools[j].Name
+	})
+
+	return lock, nil
+}
+
+// ReadLockfile reads gomodrun.lock from the package root.
+func ReadLockfile(pkgRoot string) (*Lockfile, error) {
+	data, err := ioutil.ReadFile(path.Join(pkgRoot, LockFileName))
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("%s not found, run gomodrun lock to create it", LockFileName)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	lock := &Lockfile{}
+	err = json.Unmarshal(data, lock)
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s failed: %s", LockFileName, err)
+	}
+
+	return lock, nil
+}
+
+// Lock builds every tool in the tools file and writes how they were built to gomodrun.lock in the package root.
+func Lock(pkgRoot string) (*Lockfile, error) {
+	runner, err := NewRunner(&Options{PkgRoot: pkgRoot})
+	if err != nil {
+		return nil, err
+	}
+
+	return runner.Lock()
+}
+
+// Lock builds every tool in the tools file and writes how they were built to gomodrun.lock in the package root.
+func (r *Runner) Lock() (*Lockfile, error) {
+	lock, err := r.getLockfile()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := json.MarshalIndent(lock, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	err = ioutil.WriteFile(path.Join(r.PkgRoot, LockFileName), append(data, '\n'), 0o644) //nolint:gosec // Lockfile is world readable.
+	if err != nil {
+		return nil, err
+	}
+
+	return lock, nil
+}
+
+// Verify compares gomodrun.lock with how every tool in the tools file builds now, returning every difference.
+func Verify(pkgRoot string, options *VerifyOptions) ([]LockMismatch, error) {
+	runner, err := NewRunner(&Options{PkgRoot: pkgRoot})
+	if err != nil {
+		return nil, err
+	}
+
+	return runner.Verify(options)
+}
+
+// Verify compares gomodrun.lock with how every tool in the tools file builds now, returning every difference.
+func (r *Runner) Verify(options *VerifyOptions) ([]LockMismatch, error) {
+	locked, err := ReadLockfile(r.PkgRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	if options == nil {
+		options = &VerifyOptions{}
+	}
+
+	runner := r
+	if options.Rebuild {
+		tempDir, err := ioutil.TempDir("", "gomodrun-verify")
+		if err != nil {
+			return nil, err
+		}
+		defer os.RemoveAll(tempDir) //nolint // Ignore error, not interested if it fails.
+
+		rebuildRunner := *r
+		rebuildRunner.CacheDir = tempDir
+		rebuildRunner.NoIndex = true
+		runner = &rebuildRunner
+	}
+
+	current, err := runner.getLockfile()
+	if err != nil {
+		return nil, err
+	}
+
+	return compareLockfiles(locked, current), nil
+}
+
+// isSamePlatform returns true when both tools were built for the same GOOS and GOARCH.
+func isSamePlatform(locked, current *LockedTool) bool {
+	return locked.Settings["GOOS"] == current.Settings["GOOS"] && locked.Settings["GOARCH"] == current.Settings["GOARCH"]
+}
+
+// compareLockfiles returns every difference between the locked and current tools. The binary hash, build settings
+// and linked dependencies depend on the platform, so they're only compared when the tool was locked on the same
+// GOOS and GOARCH.
+func compareLockfiles(locked, current *Lockfile) []LockMismatch {
+	mismatches := []LockMismatch{}
+	currentTools := map[string]*LockedTool{}
+	for idx := range current.Tools {
+		currentTools[current.Tools[idx].Name] = &current.Tools[idx]
+	}
+
+	lockedTools := map[string]bool{}
+	for idx := range locked.Tools {
+		lockedTool := &locked.Tools[idx]
+		lockedTools[lockedTool.Name] = true
+
+		currentTool, ok := currentTools[lockedTool.Name]
+		if !ok {
+			mismatches = append(mismatches, LockMismatch{Tool: lockedTool.Name, Field: "tool", Locked: "present", Current: "not in the tools file"})
+			continue
+		}
+
+		type field struct {
+			name            string
+			locked, current string
+		}
+		fields := []field{
+			{"importPath", lockedTool.ImportPath, currentTool.ImportPath},
+			{"module", lockedTool.Module, currentTool.Module},
+			{"version", lockedTool.Version, currentTool.Version},
+			{"sum", lockedTool.Sum, currentTool.Sum},
+			{"goVersion", lockedTool.GoVersion, currentTool.GoVersion},
+		}
+
+		samePlatform := isSamePlatform(lockedTool, currentTool)
+		if samePlatform {
+			fields = append(fields,
+				field{"deps", strings.Join(lockedTool.Deps, ", "), strings.Join(currentTool.Deps, ", ")},
+				field{"binaryHash", lockedTool.BinaryHash, currentTool.BinaryHash},
+			)
+		}
+
+		for _, field := range fields {
+			if field.locked != field.current {
+				mismatches = append(mismatches, LockMismatch{Tool: lockedTool.Name, Field: field.name, Locked: field.locked, Current: field.current})
+			}
+		}
+
+		if !samePlatform {
+			continue
+		}
+
+		keys := []string{}
+		for key := range lockedTool.Settings {
+			keys = append(keys, key)
+		}
+		for key := range currentTool.Settings {
+			if _, ok := lockedTool.Settings[key]; !ok {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			if lockedTool.Settings[key] != currentTool.Settings[key] {
+				mismatches = append(mismatches, LockMismatch{Tool: lockedTool.Name, Field: "settings." + key, Locked: lockedTool.Settings[key], Current: currentTool.Settings[key]})
+			}
+		}
+	}
+
+	for _, currentTool := range current.Tools {
+		if !lockedTools[currentTool.Name] {
+			mismatches = append(mismatches, LockMismatch{Tool: currentTool.Name, Field: "tool", Locked: "missing", Current: "in the tools file"})
+		}
+	}
+
+	return mismatches
+}
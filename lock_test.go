@@ -0,0 +1,195 @@
+package gomodrun
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("lock", func() {
+	var tempDir string
+	var goVersion string
+
+	BeforeEach(func() {
+		var err error
+		goVersion, err = getGoVersion()
+		if err != nil {
+			panic(err)
+		}
+
+		tempDir = createProjectFixture()
+	})
+
+	AfterEach(func() {
+		err := os.RemoveAll(tempDir)
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	writeLockfile := func(lock *Lockfile) {
+		data, err := json.Marshal(lock)
+		Expect(err).To(BeNil())
+		err = ioutil.WriteFile(path.Join(tempDir, LockFileName), data, 0600)
+		Expect(err).To(BeNil())
+	}
+
+	It("should record how each tool was built", func() {
+		lock, err := Lock(tempDir)
+		Expect(err).To(BeNil())
+		Expect(lock.Tools).To(HaveLen(1))
+
+		tool := lock.Tools[0]
+		Expect(tool.Name).To(Equal("hello-world"))
+		Expect(tool.ImportPath).To(Equal("github.com/dustinblackman/go-hello-world-test/hello-world"))
+		Expect(tool.Module).To(Equal("github.com/dustinblackman/go-hello-world-test"))
+		Expect(tool.Version).To(Equal("v0.0.2"))
+		Expect(tool.Sum).To(Equal("h1:DcAbKiyeohJ/c/3m5c7h3tQGKA8q7J9eahZpAjo3ZZs="))
+		Expect(tool.GoVersion).To(Equal(goVersion))
+		Expect(tool.Settings).To(HaveKeyWithValue("-trimpath", "true"))
+		Expect(tool.Settings).To(HaveKey("GOOS"))
+		Expect(tool.BinaryHash).To(HavePrefix("sha256:"))
+
+		written, err := ReadLockfile(tempDir)
+		Expect(err).To(BeNil())
+		Expect(written).To(Equal(lock))
+	})
+
+	It("should pass verify when nothing changed", func() {
+		_, err := Lock(tempDir)
+		Expect(err).To(BeNil())
+
+		mismatches, err := Verify(tempDir, &VerifyOptions{})
+		Expect(err).To(BeNil())
+		Expect(mismatches).To(BeEmpty())
+
+		mismatches, err = Verify(tempDir, &VerifyOptions{Rebuild: true})
+		Expect(err).To(BeNil())
+		Expect(mismatches).To(BeEmpty())
+	})
+
+	It("should report every field that differs from the lockfile", func() {
+		lock, err := Lock(tempDir)
+		Expect(err).To(BeNil())
+
+		binaryHash := lock.Tools[0].BinaryHash
+		lock.Tools[0].Version = "v0.0.1"
+		lock.Tools[0].BinaryHash = "sha256:0"
+		lock.Tools = append(lock.Tools, LockedTool{Name: "removed-tool"})
+		writeLockfile(lock)
+
+		mismatches, err := Verify(tempDir, &VerifyOptions{Rebuild: true})
+		Expect(err).To(BeNil())
+		Expect(mismatches).To(Equal([]LockMismatch{
+			{Tool: "hello-world", Field: "version", Locked: "v0.0.1", Current: "v0.0.2"},
+			{Tool: "hello-world", Field: "binaryHash", Locked: "sha256:0", Current: binaryHash},
+			{Tool: "removed-tool", Field: "tool", Locked: "present", Current: "not in the tools file"},
+		}))
+	})
+
+	It("should treat nil verify options as the defaults", func() {
+		_, err := Lock(tempDir)
+		Expect(err).To(BeNil())
+
+		mismatches, err := Verify(tempDir, nil)
+		Expect(err).To(BeNil())
+		Expect(mismatches).To(BeEmpty())
+	})
+
+	It("should only compare platform specific fields on the same platform", func() {
+		lock, err := Lock(tempDir)
+		Expect(err).To(BeNil())
+
+		lock.Tools[0].Version = "v0.0.1"
+		lock.Tools[0].Settings["GOOS"] = "plan9"
+		lock.Tools[0].Settings["CGO_ENABLED"] = "1"
+		lock.Tools[0].Deps = []string{}
+		lock.Tools[0].BinaryHash = "sha256:0"
+		writeLockfile(lock)
+
+		mismatches, err := Verify(tempDir, &VerifyOptions{})
+		Expect(err).To(BeNil())
+		Expect(mismatches).To(Equal([]LockMismatch{
+			{Tool: "hello-world", Field: "version", Locked: "v0.0.1", Current: "v0.0.2"},
+		}))
+	})
+
+	It("should rebuild cached binaries built without -trimpath", func() {
+		lock, err := Lock(tempDir)
+		Expect(err).To(BeNil())
+
+		resolution, err := Resolve("hello-world", &Options{PkgRoot: tempDir})
+		Expect(err).To(BeNil())
+		Expect(isUntrimmedBin(resolution.CachePath)).To(BeFalse())
+
+		srcDir := path.Join(tempDir, "untrimmed")
+		err = os.MkdirAll(srcDir, 0750)
+		Expect(err).To(BeNil())
+		err = ioutil.WriteFile(path.Join(srcDir, "go.mod"), []byte("module example.com/untrimmed\n\ngo 1.18\n"), 0600)
+		Expect(err).To(BeNil())
+		err = ioutil.WriteFile(path.Join(srcDir, "main.go"), []byte("package main\n\nfunc main() {}\n"), 0600)
+		Expect(err).To(BeNil())
+		cmd := exec.Command("go", "build", "-o", resolution.CachePath, ".")
+		cmd.Dir = srcDir
+		Expect(cmd.Run()).To(BeNil())
+		Expect(isUntrimmedBin(resolution.CachePath)).To(BeTrue())
+
+		relocked, err := Lock(tempDir)
+		Expect(err).To(BeNil())
+		Expect(relocked).To(Equal(lock))
+		Expect(isUntrimmedBin(resolution.CachePath)).To(BeFalse())
+	})
+
+	It("should only check cached binaries for -trimpath once", func() {
+		runner, err := NewRunner(&Options{PkgRoot: tempDir})
+		Expect(err).To(BeNil())
+		cachedBin, err := runner.Build("hello-world")
+		Expect(err).To(BeNil())
+		_, err = os.Stat(getTrimmedMarkerPath(cachedBin))
+		Expect(os.IsNotExist(err)).To(BeTrue())
+
+		_, err = runner.Build("hello-world")
+		Expect(err).To(BeNil())
+		_, err = os.Stat(getTrimmedMarkerPath(cachedBin))
+		Expect(err).To(BeNil())
+
+		err = removeCacheEntry(cachedBin)
+		Expect(err).To(BeNil())
+		_, err = os.Stat(getTrimmedMarkerPath(cachedBin))
+		Expect(os.IsNotExist(err)).To(BeTrue())
+	})
+
+	It("should report tools missing from the lockfile", func() {
+		writeLockfile(&Lockfile{Tools: []LockedTool{}})
+
+		mismatches, err := Verify(tempDir, &VerifyOptions{})
+		Expect(err).To(BeNil())
+		Expect(mismatches).To(Equal([]LockMismatch{
+			{Tool: "hello-world", Field: "tool", Locked: "missing", Current: "in the tools file"},
+		}))
+	})
+
+	It("should fail verify without a lockfile", func() {
+		_, err := Verify(tempDir, &VerifyOptions{})
+		Expect(err).To(MatchError("gomodrun.lock not found, run gomodrun lock to create it"))
+	})
+
+	It("should refuse to lock overridden tools", func() {
+		runner, err := NewRunner(&Options{
+			PkgRoot:   tempDir,
+			Stderr:    ioutil.Discard,
+			Overrides: map[string]string{"hello-world": tempDir},
+		})
+		Expect(err).To(BeNil())
+
+		_, err = runner.Lock()
+		Expect(err).To(MatchError("hello-world is overridden with " + tempDir + ", remove the override to lock it"))
+		_, err = os.Stat(path.Join(tempDir, LockFileName))
+		Expect(os.IsNotExist(err)).To(BeTrue())
+	})
+})
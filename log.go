@@ -0,0 +1,27 @@
+// Package gomodrun is the forgotten go tool that executes and caches binaries included in go.mod files.
+// This makes it easy to version cli tools in your projects such as `golangci-lint`
+// and `ginkgo` that are versioned locked to what you specify in `go.mod`.
+// Binaries are cached by go version and package version.
+package gomodrun
+
+import (
+	"fmt"
+	"time"
+)
+
+// Logger receives messages about each phase of finding, building and running a tool. *log.Logger satisfies it.
+type Logger interface {
+	Printf(format string, args ...interface{})
+}
+
+// logf logs a message if logger is set.
+func logf(logger Logger, format string, args ...interface{}) {
+	if logger != nil {
+		logger.Printf(format, args...)
+	}
+}
+
+// logTiming logs a message along with how long the phase took since start.
+func logTiming(logger Logger, start time.Time, format string, args ...interface{}) {
+	logf(logger, "%s in %s", fmt.Sprintf(format, args...), time.Since(start).Round(time.Microsecond))
+}
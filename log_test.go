@@ -0,0 +1,71 @@
+package gomodrun
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type recordingLogger struct {
+	messages []string
+}
+
+func (l *recordingLogger) Printf(format string, args ...interface{}) {
+	l.messages = append(l.messages, fmt.Sprintf(format, args...))
+}
+
+var _ = Describe("log", func() {
+	var tempDir string
+	var goVersion string
+
+	BeforeEach(func() {
+		var err error
+		goVersion, err = getGoVersion()
+		if err != nil {
+			panic(err)
+		}
+
+		tempDir = createProjectFixture()
+	})
+
+	AfterEach(func() {
+		err := os.RemoveAll(tempDir)
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	It("should log each phase of building and running a tool", func() {
+		logger := &recordingLogger{}
+		exitCode, err := Run("hello-world", []string{}, &Options{PkgRoot: tempDir, Stdout: ioutil.Discard, Logger: logger})
+		Expect(err).To(BeNil())
+		Expect(exitCode).To(Equal(0))
+
+		Expect(logger.messages).To(HaveLen(6))
+		Expect(logger.messages[0]).To(HavePrefix("parsed tools package " + tempDir + " in "))
+		Expect(logger.messages[1]).To(HavePrefix("parsed " + path.Join(tempDir, "go.mod") + " in "))
+		Expect(logger.messages[2]).To(Equal("hello-world is not cached, building github.com/dustinblackman/go-hello-world-test@v0.0.2/hello-world"))
+		Expect(logger.messages[3]).To(HavePrefix("found source "))
+		Expect(logger.messages[4]).To(HavePrefix("built " + path.Join(tempDir, ".gomodrun", goVersion)))
+		Expect(logger.messages[5]).To(HavePrefix("ran hello-world with exit code 0 in "))
+	})
+
+	It("should log when a cached binary is used", func() {
+		_, err := Run("hello-world", []string{}, &Options{PkgRoot: tempDir, Stdout: ioutil.Discard})
+		Expect(err).To(BeNil())
+
+		logger := &recordingLogger{}
+		_, err = Run("hello-world", []string{"1"}, &Options{PkgRoot: tempDir, Stdout: ioutil.Discard, Logger: logger})
+		Expect(err).To(BeNil())
+		Expect(logger.messages).To(ContainElement(HavePrefix("using cached " + path.Join(tempDir, ".gomodrun", goVersion))))
+		Expect(logger.messages).To(ContainElement(HavePrefix("ran hello-world with exit code 1 in ")))
+	})
+
+	It("should not log without a logger", func() {
+		Expect(func() { logf(nil, "not logged %s", "message") }).ToNot(Panic())
+	})
+})
@@ -0,0 +1,381 @@
+// Package gomodrun is the forgotten go tool that executes and caches binaries included in go.mod files.
+// This makes it easy to version cli tools in your projects such as `golangci-lint`
+// and `ginkgo` that are versioned locked to what you specify in `go.mod`.
+// Binaries are cached by go version and package version.
+package gomodrun
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"strings"
+
+	"golang.org/x/mod/module"
+	"golang.org/x/mod/semver"
+)
+
+// maxMajorVersions limits how many major versions past the current one are checked for a tool.
+const maxMajorVersions = 100
+
+// VersionOptions contains parameters that change which versions are considered by Outdated and Upgrade.
+type VersionOptions struct {
+	Prerelease bool // Include prerelease versions such as v1.2.0-rc.1.
+	Major      bool // Used by Upgrade to move to the latest major version, updating the tools file import.
+}
+
+// ToolVersion compares the version of a tool required in go.mod with the versions available from GOPROXY.
+type ToolVersion struct {
+	ImportPath      string // Package imported in the tools file.
+	Module          string // Module required in go.mod.
+	Version         string // Version required in go.mod.
+	LatestSameMajor string // Newest version of Module, which shares the major version of Version.
+	Latest          string // Newest version of any major version.
+	LatestModule    string // Module providing Latest, which includes its major version suffix such as /v2.
+}
+
+// Outdated returns true when a newer version of the tool exists with the same major version.
+func (t *ToolVersion) Outdated() bool {
+	return semver.Compare(t.LatestSameMajor, t.Version) > 0
+}
+
+// OutdatedMajor returns true when a newer major version of the tool exists.
+func (t *ToolVersion) OutdatedMajor() bool {
+	return t.LatestModule != t.Module
+}
+
+// getLatestVersion returns the newest version in versions, skipping prereleases unless enabled.
+func getLatestVersion(versions []string, options *VersionOptions) string {
+	latest := ""
+	for _, version := range versions {
+		if semver.Prerelease(version) != "" && (options == nil || !options.Prerelease) {
+			continue
+		}
+
+		if latest == "" || semver.Compare(version, latest) > 0 {
+			latest = version
+		}
+	}
+
+	return latest
+}
+
+// goProxyEnv is how the go command is configured to look up modules.
+type goProxyEnv struct {
+	goProxy   string // GOPROXY.
+	goNoProxy string // GONOPROXY, which defaults to GOPRIVATE.
+}
+
+// goEnv returns the value of key from go env, run through the toolchain.
+func (r *Runner) goEnv(key string) (string, error) {
+	output := &bytes.Buffer{}
+	err := r.toolchain().Run(r.PkgRoot, output, "env", key)
+	if err != nil {
+		return "", fmt.Errorf("go env %s failed: %s", key, output)
+	}
+
+	return strings.TrimSpace(output.String()), nil
+}
+
+// getGoProxyEnv returns GOPROXY and GONOPROXY from the toolchain.
+func (r *Runner) getGoProxyEnv() (*goProxyEnv, error) {
+	goProxy, err := r.goEnv("GOPROXY")
+	if err != nil {
+		return nil, err
+	}
+
+	goNoProxy, err := r.goEnv("GONOPROXY")
+	if err != nil {
+		return nil, err
+	}
+
+	return &goProxyEnv{goProxy: goProxy, goNoProxy: goNoProxy}, nil
+}
+
+// getModuleVersions returns all versions of modulePath. Modules matching GONOPROXY, and modules GOPROXY falls back
+// to direct for, are listed with go list -m -versions since they come from version control instead of a proxy.
+func (r *Runner) getModuleVersions(env *goProxyEnv, modulePath string) ([]string, error) {
+	if !module.MatchPrefixPatterns(env.goNoProxy, modulePath) {
+		versions, err := getModuleVersions(env.goProxy, modulePath)
+		if err != errProxyDirect {
+			return versions, err
+		}
+	}
+
+	output := &bytes.Buffer{}
+	err := r.toolchain().Run(r.PkgRoot, output, "list", "-m", "-versions", modulePath)
+	if err != nil {
+		if isModuleNotFoundOutput(output.String()) {
+			return nil, errModuleNotFound
+		}
+		return nil, fmt.Errorf("go list -m -versions %s failed: %s", modulePath, strings.TrimSpace(output.String()))
+	}
+
+	for _, line := range strings.Split(output.String(), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) > 0 && fields[0] == modulePath {
+			versions := fields[1:]
+			semver.Sort(versions)
+			return versions, nil
+		}
+	}
+
+	return nil, errModuleNotFound
+}
+
+// isModuleNotFoundOutput returns true when go list failed because the module doesn't exist.
+func isModuleNotFoundOutput(output string) bool {
+	for _, notFound := range []string{
+		"not found", "404", "410", "no such file", "no matching versions", "unrecognized import path",
+	} {
+		if strings.Contains(output, notFound) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// getToolVersion looks up the latest versions of the module providing a tool.
+func (r *Runner) getToolVersion(
+	env *goProxyEnv, importPath, modulePath, version string, options *VersionOptions,
+) (*ToolVersion, error) {
+	toolVersion := &ToolVersion{
+		ImportPath:   importPath,
+		Module:       modulePath,
+		Version:      version,
+		LatestModule: modulePath,
+	}
+
+	versions, err := r.getModuleVersions(env, modulePath)
+	if err != nil {
+		return nil, fmt.Errorf("listing versions of %s failed: %s", modulePath, err)
+	}
+
+	toolVersion.LatestSameMajor = getLatestVersion(versions, options)
+	if semver.Compare(toolVersion.LatestSameMajor, version) < 0 {
+		toolVersion.LatestSameMajor = version
+	}
+	toolVersion.Latest = toolVersion.LatestSameMajor
+
+	prefix, pathMajor, ok := module.SplitPathVersion(modulePath)
+	if !ok || strings.HasPrefix(pathMajor, ".") {
+		// gopkg.in modules encode the major version differently and are not checked for newer major versions.
+		return toolVersion, nil
+	}
+
+	major := 1
+	if pathMajor != "" {
+		major, err = strconv.Atoi(strings.TrimPrefix(pathMajor, "/v"))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for next := major + 1; next <= major+maxMajorVersions; next++ {
+		nextModule := fmt.Sprintf("%s/v%d", prefix, next)
+		versions, err = r.getModuleVersions(env, nextModule)
+		if err == errModuleNotFound {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("listing versions of %s failed: %s", nextModule, err)
+		}
+
+		latest := getLatestVersion(versions, options)
+		if latest == "" {
+			break
+		}
+		toolVersion.Latest = latest
+		toolVersion.LatestModule = nextModule
+	}
+
+	return toolVersion, nil
+}
+
+// Outdated compares the version of every tool in the tools file with the latest versions available from GOPROXY.
+func Outdated(pkgRoot string, options *VersionOptions) ([]*ToolVersion, error) {
+	runner, err := NewRunner(&Options{PkgRoot: pkgRoot})
+	if err != nil {
+		return nil, err
+	}
+
+	return runner.Outdated(options)
+}
+
+// Outdated compares the version of every tool in the tools file with the latest versions available from GOPROXY.
+func (r *Runner) Outdated(options *VersionOptions) ([]*ToolVersion, error) {
+	if options == nil {
+		options = &VersionOptions{}
+	}
+
+	pkg, err := getToolsPkg(r.PkgRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	mod, err := getGoMod(r.PkgRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	env, err := r.getGoProxyEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	toolVersions := []*ToolVersion{}
+	modules := map[string]*ToolVersion{}
+	for _, importPath := range pkg.Imports {
+		req := getRequire(mod, importPath)
+		if req == nil {
+			return nil, fmt.Errorf("cant find require for module %s in go.mod", importPath)
+		}
+
+		toolVersion, ok := modules[req.Mod.Path]
+		if !ok {
+			toolVersion, err = r.getToolVersion(env, importPath, req.Mod.Path, req.Mod.Version, options)
+			if err != nil {
+				return nil, err
+			}
+			modules[req.Mod.Path] = toolVersion
+		}
+
+		moduleVersion := *toolVersion
+		moduleVersion.ImportPath = importPath
+		toolVersions = append(toolVersions, &moduleVersion)
+	}
+
+	return toolVersions, nil
+}
+
+// Upgrade bumps the go.mod require of each tool to its latest version with the same major version, or all tools when
+// tools is empty. Tools can be package paths or binary names. With options.Major, tools move to their latest major
+// version and the tools file import is updated to the new major version suffix. Returns the versions of the upgraded
+// tools before upgrading.
+func Upgrade(pkgRoot string, tools []string, options *VersionOptions) ([]*ToolVersion, error) {
+	runner, err := NewRunner(&Options{PkgRoot: pkgRoot})
+	if err != nil {
+		return nil, err
+	}
+
+	return runner.Upgrade(tools, options)
+}
+
+// Upgrade bumps the go.mod require of each tool to its latest version, see Upgrade.
+func (r *Runner) Upgrade(tools []string, options *VersionOptions) ([]*ToolVersion, error) {
+	if options == nil {
+		options = &VersionOptions{}
+	}
+
+	toolVersions, err := r.Outdated(options)
+	if err != nil {
+		return nil, err
+	}
+
+	importPaths := map[string]bool{}
+	for _, tool := range tools {
+		importPath, err := getToolImportPath(r.PkgRoot, tool)
+		if err != nil {
+			return nil, err
+		}
+		importPaths[importPath] = true
+	}
+
+	toolsFiles, err := getToolsFiles(r.PkgRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	srcs := map[string][]byte{}
+	for _, toolsFile := range toolsFiles {
+		srcs[toolsFile], err = ioutil.ReadFile(toolsFile)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	changedFiles := map[string]bool{}
+	upgraded := []*ToolVersion{}
+	getArgs := []string{"get"}
+	seenArgs := map[string]bool{}
+	addGetArg := func(arg string) {
+		if !seenArgs[arg] {
+			seenArgs[arg] = true
+			getArgs = append(getArgs, arg)
+		}
+	}
+	for _, toolVersion := range toolVersions {
+		if len(importPaths) > 0 && !importPaths[toolVersion.ImportPath] {
+			continue
+		}
+
+		if options.Major && toolVersion.OutdatedMajor() {
+			newImportPath := toolVersion.LatestModule + strings.TrimPrefix(toolVersion.ImportPath, toolVersion.Module)
+			// A tool can be imported from more than one tools file, update the import in all of them.
+			for _, toolsFile := range toolsFiles {
+				found, err := hasToolImport(srcs[toolsFile], toolVersion.ImportPath)
+				if err != nil {
+					return nil, err
+				}
+				if !found {
+					continue
+				}
+
+				src, err := removeToolImport(srcs[toolsFile], toolVersion.ImportPath)
+				if err != nil {
+					return nil, err
+				}
+
+				srcs[toolsFile], err = addToolImport(src, newImportPath)
+				if err != nil {
+					return nil, err
+				}
+				changedFiles[toolsFile] = true
+			}
+
+			addGetArg(toolVersion.LatestModule + "@" + toolVersion.Latest)
+			upgraded = append(upgraded, toolVersion)
+			continue
+		}
+
+		if toolVersion.Outdated() {
+			addGetArg(toolVersion.Module + "@" + toolVersion.LatestSameMajor)
+			upgraded = append(upgraded, toolVersion)
+		}
+	}
+
+	if len(upgraded) == 0 {
+		return upgraded, nil
+	}
+
+	snapshot, err := getModSnapshot(r.PkgRoot, toolsFiles...)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, toolsFile := range toolsFiles {
+		if !changedFiles[toolsFile] {
+			continue
+		}
+
+		err = ioutil.WriteFile(toolsFile, srcs[toolsFile], 0o644) //nolint:gosec // Source files are world readable.
+		if err != nil {
+			snapshot.restore()
+			return nil, err
+		}
+	}
+
+	err = r.runGo(getArgs...)
+	if err == nil {
+		err = r.runGo("mod", "tidy")
+	}
+
+	if err != nil {
+		// Restore the tools files, go.mod and go.sum so a failed upgrade leaves the project untouched.
+		snapshot.restore()
+		return nil, err
+	}
+
+	return upgraded, nil
+}
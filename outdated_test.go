@@ -0,0 +1,354 @@
+package gomodrun
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"golang.org/x/mod/module"
+	"golang.org/x/mod/zip"
+)
+
+// createFileProxy writes a GOPROXY compatible directory containing a fake tool module for each module version.
+func createFileProxy(proxyDir string, versions []module.Version) {
+	for _, version := range versions {
+		binName := "fake-tool"
+		srcDir := path.Join(proxyDir, "src", version.Path, version.Version)
+		modFile := fmt.Sprintf("module %s\n\ngo 1.13\n", version.Path)
+		mainFile := fmt.Sprintf("package main\n\nfunc main() {\n\tprintln(%q)\n}\n", version.Version)
+
+		err := os.MkdirAll(path.Join(srcDir, "cmd", binName), 0750)
+		if err != nil {
+			panic(err)
+		}
+
+		err = ioutil.WriteFile(path.Join(srcDir, "go.mod"), []byte(modFile), 0600)
+		if err != nil {
+			panic(err)
+		}
+
+		err = ioutil.WriteFile(path.Join(srcDir, "cmd", binName, "main.go"), []byte(mainFile), 0600)
+		if err != nil {
+			panic(err)
+		}
+
+		versionDir := path.Join(proxyDir, version.Path, "@v")
+		err = os.MkdirAll(versionDir, 0750)
+		if err != nil {
+			panic(err)
+		}
+
+		zipFile, err := os.Create(path.Join(versionDir, version.Version+".zip"))
+		if err != nil {
+			panic(err)
+		}
+
+		err = zip.CreateFromDir(zipFile, version, srcDir)
+		if err != nil {
+			panic(err)
+		}
+
+		err = zipFile.Close()
+		if err != nil {
+			panic(err)
+		}
+
+		err = ioutil.WriteFile(path.Join(versionDir, version.Version+".mod"), []byte(modFile), 0600)
+		if err != nil {
+			panic(err)
+		}
+
+		info := fmt.Sprintf(`{"Version":%q,"Time":"2021-01-01T00:00:00Z"}`, version.Version)
+		err = ioutil.WriteFile(path.Join(versionDir, version.Version+".info"), []byte(info), 0600)
+		if err != nil {
+			panic(err)
+		}
+
+		list, _ := ioutil.ReadFile(path.Join(versionDir, "list")) //nolint // The list may not exist yet.
+		list = append(list, []byte(version.Version+"\n")...)
+		err = ioutil.WriteFile(path.Join(versionDir, "list"), list, 0600)
+		if err != nil {
+			panic(err)
+		}
+	}
+}
+
+// listVersionsToolchain runs go commands with Toolchain except for go list -m -versions, which lists versions from
+// modules.
+type listVersionsToolchain struct {
+	Toolchain
+	modules map[string]string
+	listed  []string
+}
+
+func (t *listVersionsToolchain) Run(dir string, output io.Writer, args ...string) error {
+	if args[0] != "list" {
+		return t.Toolchain.Run(dir, output, args...)
+	}
+
+	modulePath := args[len(args)-1]
+	t.listed = append(t.listed, modulePath)
+	versions, ok := t.modules[modulePath]
+	if !ok {
+		fmt.Fprintf(output, "go: module %s: git ls-remote -q origin: exit status 128:\n\tremote: Repository not found.\n", modulePath)
+		return errors.New("exit status 1")
+	}
+
+	fmt.Fprintf(output, "%s %s\n", modulePath, versions)
+	return nil
+}
+
+var _ = Describe("outdated", func() {
+	var tempDir string
+	var pkgRoot string
+	var env map[string]string
+
+	BeforeEach(func() {
+		var err error
+		tempDir, err = ioutil.TempDir(os.TempDir(), "gomodrun-outdated")
+		if err != nil {
+			panic(err)
+		}
+
+		proxyDir := path.Join(tempDir, "proxy")
+		createFileProxy(proxyDir, []module.Version{
+			{Path: "example.com/fake-tool", Version: "v1.0.0"},
+			{Path: "example.com/fake-tool", Version: "v1.1.0"},
+			{Path: "example.com/fake-tool", Version: "v1.2.0-rc.1"},
+			{Path: "example.com/fake-tool/v2", Version: "v2.0.0"},
+		})
+
+		env = map[string]string{
+			"GOPROXY":    "file://" + proxyDir,
+			"GOSUMDB":    "off",
+			"GOFLAGS":    "-mod=mod -modcacherw",
+			"GOMODCACHE": path.Join(tempDir, "modcache"),
+		}
+		for key, value := range env {
+			env[key] = os.Getenv(key)
+			err = os.Setenv(key, value)
+			if err != nil {
+				panic(err)
+			}
+		}
+
+		pkgRoot = path.Join(tempDir, "project")
+		err = os.MkdirAll(pkgRoot, 0750)
+		if err != nil {
+			panic(err)
+		}
+
+		err = ioutil.WriteFile(path.Join(pkgRoot, "go.mod"), []byte("module example.com/project\n\ngo 1.13\n\nrequire example.com/fake-tool v1.0.0\n"), 0600)
+		if err != nil {
+			panic(err)
+		}
+
+		err = ioutil.WriteFile(path.Join(pkgRoot, "tools.go"), []byte("// +build tools\n\npackage project\n\nimport (\n\t_ \"example.com/fake-tool/cmd/fake-tool\"\n)\n"), 0600)
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	AfterEach(func() {
+		for key, value := range env {
+			err := os.Setenv(key, value)
+			if err != nil {
+				panic(err)
+			}
+		}
+
+		err := os.RemoveAll(tempDir)
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	Context("Outdated", func() {
+		It("should return the latest versions for the same and newer major versions", func() {
+			toolVersions, err := Outdated(pkgRoot, &VersionOptions{})
+			Expect(err).To(BeNil())
+			Expect(toolVersions).To(HaveLen(1))
+			Expect(*toolVersions[0]).To(Equal(ToolVersion{
+				ImportPath:      "example.com/fake-tool/cmd/fake-tool",
+				Module:          "example.com/fake-tool",
+				Version:         "v1.0.0",
+				LatestSameMajor: "v1.1.0",
+				Latest:          "v2.0.0",
+				LatestModule:    "example.com/fake-tool/v2",
+			}))
+			Expect(toolVersions[0].Outdated()).To(BeTrue())
+			Expect(toolVersions[0].OutdatedMajor()).To(BeTrue())
+		})
+
+		It("should include prereleases when enabled", func() {
+			toolVersions, err := Outdated(pkgRoot, &VersionOptions{Prerelease: true})
+			Expect(err).To(BeNil())
+			Expect(toolVersions[0].LatestSameMajor).To(Equal("v1.2.0-rc.1"))
+		})
+
+		It("should treat nil options as defaults", func() {
+			toolVersions, err := Outdated(pkgRoot, nil)
+			Expect(err).To(BeNil())
+			Expect(toolVersions[0].LatestSameMajor).To(Equal("v1.1.0"))
+		})
+
+		It("should list versions with the go command for modules matching GONOPROXY", func() {
+			goNoProxy := os.Getenv("GONOPROXY")
+			defer os.Setenv("GONOPROXY", goNoProxy) //nolint // Ignore error, restoring the environment.
+			err := os.Setenv("GONOPROXY", "example.com/fake-tool")
+			Expect(err).To(BeNil())
+
+			runner, err := NewRunner(&Options{PkgRoot: pkgRoot})
+			Expect(err).To(BeNil())
+			toolchain := &listVersionsToolchain{
+				Toolchain: runner.toolchain(),
+				modules:   map[string]string{"example.com/fake-tool": "v1.0.0 v1.3.0"},
+			}
+			runner.Toolchain = toolchain
+
+			toolVersions, err := runner.Outdated(nil)
+			Expect(err).To(BeNil())
+			Expect(toolVersions[0].LatestSameMajor).To(Equal("v1.3.0"))
+			Expect(toolVersions[0].OutdatedMajor()).To(BeFalse())
+			Expect(toolchain.listed).To(Equal([]string{"example.com/fake-tool", "example.com/fake-tool/v2"}))
+		})
+
+		It("should list versions with the go command when GOPROXY falls back to direct", func() {
+			err := os.Setenv("GOPROXY", "direct")
+			Expect(err).To(BeNil())
+
+			runner, err := NewRunner(&Options{PkgRoot: pkgRoot})
+			Expect(err).To(BeNil())
+			runner.Toolchain = &listVersionsToolchain{
+				Toolchain: runner.toolchain(),
+				modules: map[string]string{
+					"example.com/fake-tool":    "v1.0.0",
+					"example.com/fake-tool/v2": "v2.0.0 v2.1.0",
+				},
+			}
+
+			toolVersions, err := runner.Outdated(nil)
+			Expect(err).To(BeNil())
+			Expect(toolVersions[0].Latest).To(Equal("v2.1.0"))
+			Expect(toolVersions[0].LatestModule).To(Equal("example.com/fake-tool/v2"))
+		})
+
+		It("should return an error when the module can not be found", func() {
+			_, err := getModuleVersions(os.Getenv("GOPROXY"), "example.com/not-real")
+			Expect(err).To(Equal(errModuleNotFound))
+		})
+
+		It("should time out when the proxy stalls", func() {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				time.Sleep(time.Second)
+			}))
+			defer server.Close()
+
+			timeout := proxyClient.Timeout
+			proxyClient.Timeout = 50 * time.Millisecond
+			defer func() {
+				proxyClient.Timeout = timeout
+			}()
+
+			_, err := getModuleVersions(server.URL, "example.com/fake-tool")
+			Expect(err).To(MatchError(ContainSubstring("Client.Timeout exceeded")))
+		})
+
+		It("should return errProxyDirect when the lookup falls back to direct", func() {
+			_, err := getModuleVersions("direct", "example.com/fake-tool")
+			Expect(err).To(Equal(errProxyDirect))
+		})
+	})
+
+	Context("Upgrade", func() {
+		It("should upgrade to the latest version with the same major version", func() {
+			upgraded, err := Upgrade(pkgRoot, []string{"fake-tool"}, &VersionOptions{})
+			Expect(err).To(BeNil())
+			Expect(upgraded).To(HaveLen(1))
+
+			mod, err := getGoMod(pkgRoot)
+			Expect(err).To(BeNil())
+			Expect(mod.Require).To(HaveLen(1))
+			Expect(mod.Require[0].Mod).To(Equal(module.Version{Path: "example.com/fake-tool", Version: "v1.1.0"}))
+		})
+
+		It("should upgrade to the latest major version and update the tools file", func() {
+			upgraded, err := Upgrade(pkgRoot, []string{}, &VersionOptions{Major: true})
+			Expect(err).To(BeNil())
+			Expect(upgraded).To(HaveLen(1))
+
+			mod, err := getGoMod(pkgRoot)
+			Expect(err).To(BeNil())
+			Expect(mod.Require).To(HaveLen(1))
+			Expect(mod.Require[0].Mod).To(Equal(module.Version{Path: "example.com/fake-tool/v2", Version: "v2.0.0"}))
+
+			content, err := ioutil.ReadFile(path.Join(pkgRoot, "tools.go"))
+			Expect(err).To(BeNil())
+			Expect(strings.Contains(string(content), `_ "example.com/fake-tool/v2/cmd/fake-tool"`)).To(BeTrue())
+		})
+
+		It("should update the import in every tools file when upgrading the major version", func() {
+			err := ioutil.WriteFile(path.Join(pkgRoot, "tools_more.go"), []byte("// +build tools\n\npackage project\n\nimport (\n\t_ \"example.com/fake-tool/cmd/fake-tool\"\n)\n"), 0600)
+			Expect(err).To(BeNil())
+
+			_, err = Upgrade(pkgRoot, []string{}, &VersionOptions{Major: true})
+			Expect(err).To(BeNil())
+
+			for _, toolsFile := range []string{"tools.go", "tools_more.go"} {
+				content, err := ioutil.ReadFile(path.Join(pkgRoot, toolsFile))
+				Expect(err).To(BeNil())
+				Expect(string(content)).To(ContainSubstring(`_ "example.com/fake-tool/v2/cmd/fake-tool"`))
+				Expect(string(content)).ToNot(ContainSubstring(`_ "example.com/fake-tool/cmd/fake-tool"`))
+			}
+		})
+
+		It("should treat nil options as defaults", func() {
+			upgraded, err := Upgrade(pkgRoot, []string{}, nil)
+			Expect(err).To(BeNil())
+			Expect(upgraded).To(HaveLen(1))
+			Expect(upgraded[0].LatestSameMajor).To(Equal("v1.1.0"))
+		})
+
+		It("should restore go.mod and go.sum when go mod tidy fails", func() {
+			err := os.MkdirAll(path.Join(pkgRoot, "broken"), 0750)
+			Expect(err).To(BeNil())
+			err = ioutil.WriteFile(path.Join(pkgRoot, "broken", "broken.go"), []byte("package broken\n\nimport _ \"example.invalid/missing\"\n"), 0600)
+			Expect(err).To(BeNil())
+			goMod, err := ioutil.ReadFile(path.Join(pkgRoot, "go.mod"))
+			Expect(err).To(BeNil())
+
+			_, err = Upgrade(pkgRoot, []string{}, &VersionOptions{Major: true})
+			Expect(err).To(MatchError(ContainSubstring("go mod tidy failed")))
+
+			content, err := ioutil.ReadFile(path.Join(pkgRoot, "go.mod"))
+			Expect(err).To(BeNil())
+			Expect(string(content)).To(Equal(string(goMod)))
+
+			_, err = os.Stat(path.Join(pkgRoot, "go.sum"))
+			Expect(os.IsNotExist(err)).To(BeTrue())
+
+			content, err = ioutil.ReadFile(path.Join(pkgRoot, "tools.go"))
+			Expect(err).To(BeNil())
+			Expect(string(content)).To(ContainSubstring(`_ "example.com/fake-tool/cmd/fake-tool"`))
+		})
+
+		It("should not change anything when tools are up to date", func() {
+			_, err := Upgrade(pkgRoot, []string{}, &VersionOptions{})
+			Expect(err).To(BeNil())
+
+			upgraded, err := Upgrade(pkgRoot, []string{}, &VersionOptions{})
+			Expect(err).To(BeNil())
+			Expect(upgraded).To(BeEmpty())
+		})
+	})
+})
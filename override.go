@@ -0,0 +1,96 @@
+// Package gomodrun is the forgotten go tool that executes and caches binaries included in go.mod files.
+// This makes it easy to version cli tools in your projects such as `golangci-lint`
+// and `ginkgo` that are versioned locked to what you specify in `go.mod`.
+// Binaries are cached by go version and package version.
+package gomodrun
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// OverrideEnvPrefix is prefixed to a tool's binary name to set an override from the environment, such as
+	// GOMODRUN_OVERRIDE_golangci-lint=../golangci-lint. The binary name can also be upper case with dashes replaced by
+	// underscores, such as GOMODRUN_OVERRIDE_GOLANGCI_LINT.
+	OverrideEnvPrefix = "GOMODRUN_OVERRIDE_"
+
+	// overridesDirName is the directory in each go version directory containing binaries built from overrides.
+	overridesDirName = "overrides"
+
+	// localOverrideVersion is the version reported for tools built from a local directory.
+	localOverrideVersion = "local"
+)
+
+// getOverride returns the source binName is overridden with from Overrides or the environment, or an empty string
+// when it isn't overridden.
+func (r *Runner) getOverride(binName string) string {
+	if source, ok := r.Overrides[binName]; ok {
+		return source
+	}
+
+	for _, key := range []string{
+		OverrideEnvPrefix + binName,
+		OverrideEnvPrefix + strings.ToUpper(strings.ReplaceAll(binName, "-", "_")),
+	} {
+		if source := getEnvValue(r.Env, key); source != "" {
+			return source
+		}
+	}
+
+	return ""
+}
+
+// isLocalOverride returns true when source is a local directory rather than a @<commit> or @<version> query.
+func isLocalOverride(source string) bool {
+	return !strings.HasPrefix(source, "@")
+}
+
+// applyOverride points resolution at source, a local module directory or a @<commit> to download, in place of the
+// version required in go.mod.
+func (r *Runner) applyOverride(resolution *Resolution, source string) error {
+	resolution.Override = source
+	resolution.Sum = ""
+
+	if isLocalOverride(source) {
+		moduleDir, err := filepath.Abs(source)
+		if err != nil {
+			return err
+		}
+
+		if info, err := os.Stat(moduleDir); err != nil || !info.IsDir() {
+			return fmt.Errorf("override %s for %s is not a directory", source, resolution.BinName)
+		}
+
+		// Local directories are labeled by their path so each gets its own cache slot.
+		hash := sha256.Sum256([]byte(moduleDir))
+		resolution.Version = localOverrideVersion
+		resolution.CmdPath = path.Join(resolution.Module+"@"+localOverrideVersion+"-"+hex.EncodeToString(hash[:4]), resolution.Subpackage)
+		resolution.SourceDir = path.Join(filepath.ToSlash(moduleDir), resolution.Subpackage)
+
+		return nil
+	}
+
+	download, err := r.downloadModule(resolution.Module + source)
+	if err != nil {
+		return fmt.Errorf("downloading override %s for %s failed: %s", source, resolution.BinName, err)
+	}
+
+	resolution.Version = download.Version
+	resolution.CmdPath = path.Join(resolution.Module+"@"+download.Version, resolution.Subpackage)
+	resolution.SourceDir = path.Join(filepath.ToSlash(download.Dir), resolution.Subpackage)
+
+	return nil
+}
+
+// warnOverride prints a warning to Stderr that resolution is built from an override.
+func (r *Runner) warnOverride(resolution *Resolution) {
+	if r.Stderr != nil {
+		fmt.Fprintf(r.Stderr, "gomodrun: warning: %s is overridden with %s instead of go.mod, using %s\n", strings.TrimSuffix(resolution.BinName, ".exe"), resolution.Override, resolution.CachePath)
+	}
+}
@@ -0,0 +1,115 @@
+package gomodrun
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("override", func() {
+	var tempDir string
+	var forkDir string
+	var toolchain *fakeToolchain
+	var runner *Runner
+	var stderr *bytes.Buffer
+
+	BeforeEach(func() {
+		tempDir = createProjectFixture()
+		forkDir = path.Join(tempDir, "fork")
+		err := os.MkdirAll(path.Join(forkDir, "hello-world"), 0750)
+		Expect(err).To(BeNil())
+		err = ioutil.WriteFile(path.Join(forkDir, "go.mod"), []byte("module github.com/dustinblackman/go-hello-world-test\n"), 0600)
+		Expect(err).To(BeNil())
+
+		toolchain = &fakeToolchain{}
+		stderr = &bytes.Buffer{}
+		runner, err = NewRunner(&Options{PkgRoot: tempDir, Stderr: stderr})
+		Expect(err).To(BeNil())
+		runner.Toolchain = toolchain
+		runner.Sources = &fakeSources{dir: path.Join(tempDir, "src")}
+	})
+
+	AfterEach(func() {
+		err := os.RemoveAll(tempDir)
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	It("should build local overrides in their own cache slot on every build", func() {
+		runner.Overrides = map[string]string{"hello-world": forkDir}
+
+		resolution, err := runner.Resolve("hello-world")
+		Expect(err).To(BeNil())
+		Expect(resolution.Override).To(Equal(forkDir))
+		Expect(resolution.Version).To(Equal("local"))
+		Expect(resolution.Sum).To(BeEmpty())
+		Expect(resolution.SourceDir).To(Equal(path.Join(forkDir, "hello-world")))
+		Expect(resolution.CachePath).To(HavePrefix(path.Join(tempDir, ".gomodrun", "go9.9.9", "overrides", "github.com/dustinblackman/go-hello-world-test@local-")))
+
+		for i := 0; i < 2; i++ {
+			cachedBin, err := runner.Build("hello-world")
+			Expect(err).To(BeNil())
+			Expect(cachedBin).To(Equal(resolution.CachePath))
+		}
+		Expect(toolchain.calls).To(HaveLen(2))
+		Expect(toolchain.dirs).To(Equal([]string{resolution.SourceDir, resolution.SourceDir}))
+		Expect(stderr.String()).To(Equal(
+			"gomodrun: warning: hello-world is overridden with " + forkDir + " instead of go.mod, using " + resolution.CachePath + "\n" +
+				"gomodrun: warning: hello-world is overridden with " + forkDir + " instead of go.mod, using " + resolution.CachePath + "\n",
+		))
+	})
+
+	It("should read overrides from the environment", func() {
+		runner.Env = []string{OverrideEnvPrefix + "HELLO_WORLD=" + forkDir}
+		resolution, err := runner.Resolve("hello-world")
+		Expect(err).To(BeNil())
+		Expect(resolution.Override).To(Equal(forkDir))
+
+		runner.Env = []string{OverrideEnvPrefix + "hello-world=" + forkDir}
+		resolution, err = runner.Resolve("hello-world")
+		Expect(err).To(BeNil())
+		Expect(resolution.Override).To(Equal(forkDir))
+	})
+
+	It("should download commit overrides outside of the package", func() {
+		downloadDir := path.Join(tempDir, "download")
+		err := os.MkdirAll(path.Join(downloadDir, "hello-world"), 0750)
+		Expect(err).To(BeNil())
+		err = ioutil.WriteFile(path.Join(downloadDir, "go.mod"), []byte("module github.com/dustinblackman/go-hello-world-test\n"), 0600)
+		Expect(err).To(BeNil())
+
+		toolchain.downloads = map[string]string{
+			"github.com/dustinblackman/go-hello-world-test@abcdef1": `go: downloading github.com/dustinblackman/go-hello-world-test v0.0.3-0.20240101000000-abcdef123456
+{"Path": "github.com/dustinblackman/go-hello-world-test", "Version": "v0.0.3-0.20240101000000-abcdef123456", "Dir": "` + downloadDir + `"}`,
+		}
+		runner.Overrides = map[string]string{"hello-world": "@abcdef1"}
+
+		cachedBin, err := runner.Build("hello-world")
+		Expect(err).To(BeNil())
+		Expect(cachedBin).To(Equal(path.Join(tempDir, ".gomodrun", "go9.9.9", "overrides", "github.com/dustinblackman/go-hello-world-test@v0.0.3-0.20240101000000-abcdef123456", "hello-world", "hello-world")))
+		Expect(toolchain.calls[0]).To(Equal("mod download -json github.com/dustinblackman/go-hello-world-test@abcdef1"))
+		Expect(toolchain.dirs[0]).ToNot(Equal(tempDir))
+		Expect(toolchain.dirs[1]).To(Equal(path.Join(downloadDir, "hello-world")))
+
+		_, err = runner.Build("hello-world")
+		Expect(err).To(BeNil())
+		Expect(toolchain.calls).To(HaveLen(3))
+	})
+
+	It("should return an error when a commit override can't be downloaded", func() {
+		runner.Overrides = map[string]string{"hello-world": "@missing"}
+		_, err := runner.Resolve("hello-world")
+		Expect(err).To(MatchError("downloading override @missing for hello-world failed: github.com/dustinblackman/go-hello-world-test@missing: not found"))
+	})
+
+	It("should return an error when a local override does not exist", func() {
+		runner.Overrides = map[string]string{"hello-world": path.Join(tempDir, "missing")}
+		_, err := runner.Resolve("hello-world")
+		Expect(err).To(MatchError("override " + path.Join(tempDir, "missing") + " for hello-world is not a directory"))
+	})
+})
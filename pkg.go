@@ -5,9 +5,10 @@
 package gomodrun
 
 import (
+	"bytes"
+	"debug/buildinfo"
 	"errors"
 	"fmt"
-	"go/build"
 	"io"
 	"io/ioutil"
 	"os"
@@ -18,18 +19,29 @@ import (
 	"runtime"
 	"strings"
 	"syscall"
-	"unicode"
+	"time"
 
 	"github.com/otiai10/copy"
 )
 
+var versionedDepMatcher = regexp.MustCompile(`/v\d$`)
+
 // Options contains parameters that are passed to `exec.Command` when running the binary.
 type Options struct {
 	Stdin   io.Reader // Stdin passed to tool.
 	Stdout  io.Writer // Stdout passed to tool.
 	Stderr  io.Writer // Stderr passed to tool.
-	Env     []string  // Array of environment variables passed to tool.
+	Env     []string  // Array of environment variables passed to tool. Defaults to the current environment when nil.
 	PkgRoot string    // Root directory of go.mod with tools.
+	Logger  Logger    // Logger receiving each phase and its duration, nil disables logging.
+
+	Dir      string   // Working directory of the tool. Defaults to the current directory.
+	ExtraEnv []string // Environment variables such as KEY=value set over Env and the tool's config.
+
+	Overrides map[string]string // Sources to build tools from instead of go.mod by binary name, see GOMODRUN_OVERRIDE_.
+
+	Progress    io.Writer // Receives a line when a tool starts building, usually a terminal.
+	BuildOutput io.Writer // Receives `go mod download` and `go build` output as it runs. Builds are always logged next to the binary.
 }
 
 // GetPkgRoot gets your projects package root, allowing you to run gomodrun from any sub directory.
@@ -55,188 +67,521 @@ func GetPkgRoot() (string, error) {
 	}
 }
 
+// getBinName returns the name of the binary built from a tools file import, skipping major version suffixes.
+func getBinName(modulePath string) string {
+	if versionedDepMatcher.MatchString(modulePath) {
+		return path.Base(path.Dir(modulePath))
+	}
+
+	return path.Base(modulePath)
+}
+
+// GetToolBinNames returns the names of all binaries imported in the tools file.
+func GetToolBinNames(pkgRoot string) ([]string, error) {
+	return (&Runner{Options: Options{PkgRoot: pkgRoot}}).List()
+}
+
+// List returns the names of all binaries imported in the tools file.
+func (r *Runner) List() ([]string, error) {
+	pkg, err := getToolsPkg(r.PkgRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	binNames := []string{}
+	seen := map[string]bool{}
+	for _, modulePath := range pkg.Imports {
+		binName := getBinName(modulePath)
+		if seen[binName] {
+			continue
+		}
+		seen[binName] = true
+		binNames = append(binNames, binName)
+	}
+
+	return binNames, nil
+}
+
+// Resolution describes how a tool in the tools file resolves to a module, its source and its cached binary.
+type Resolution struct {
+	ImportPath string // Import path in the tools file, such as github.com/golangci/golangci-lint/cmd/golangci-lint.
+	Module     string // Module path required in go.mod, such as github.com/golangci/golangci-lint.
+	Version    string // Module version required in go.mod.
+	Subpackage string // Path of the package within the module, empty when the module root is the tool.
+	Replace    string // Replacement in go.mod, such as ../fork or example.com/fork@v1.0.0. Empty when not replaced.
+	Sum        string // Module hash from go.sum, such as h1:abc=. Empty when go.sum doesn't list the module.
+	CmdPath    string // Module path, version and subpackage, such as github.com/org/tool@v1.0.0/cmd/tool.
+	SourceDir  string // Directory the tool is built from. It may not exist until the tool is first built.
+	BinName    string // Name of the built binary.
+	GoVersion  string // Go version the binary is built with.
+	CachePath  string // Path to the cached binary. It may not exist until the tool is first built.
+	Override   string // Source the tool is built from instead of go.mod, such as ../golangci-lint or @<commit>.
+	Ephemeral  bool   // True when the tool was given as module/pkg@version instead of being in the tools file.
+}
+
 // GetCommandVersionedPkgPath extracts the command line tools package path and version from go.mod.
 func GetCommandVersionedPkgPath(pkgRoot, binName string) (string, error) {
+	resolution, err := (&Runner{Options: Options{PkgRoot: pkgRoot}}).Resolve(binName)
+	if err != nil {
+		return "", err
+	}
+
+	return resolution.CmdPath, nil
+}
+
+// Resolve returns how binName resolves to a module, its source and its cached binary without building it. binName
+// can also be module/pkg@version to resolve a tool that isn't in the tools file.
+func Resolve(binName string, options *Options) (*Resolution, error) {
+	runner, err := NewRunner(options)
+	if err != nil {
+		return nil, err
+	}
+
+	return runner.Resolve(binName)
+}
+
+// Resolve returns how binName resolves to a module, its source and its cached binary without building it.
+func (r *Runner) Resolve(binName string) (*Resolution, error) {
+	return r.resolve(binName, true)
+}
+
+// resolve resolves binName, only asking Sources for the source directory when locate is set as it may download.
+func (r *Runner) resolve(binName string, locate bool) (*Resolution, error) {
+	if isEphemeral(binName) {
+		return r.resolveEphemeral(binName, true)
+	}
+
 	if strings.HasSuffix(binName, ".exe") {
 		binName = strings.ReplaceAll(binName, ".exe", "")
 	}
 
-	pkg, err := getToolsPkg(pkgRoot)
+	override := r.getOverride(binName)
+	indexKey := ""
+	if r.useIndex() && override == "" {
+		start := time.Now()
+		key, err := r.getIndexKey()
+		if err == nil {
+			indexKey = key
+			if resolution := r.getIndexedResolution(indexKey, binName); resolution != nil {
+				logTiming(r.Logger, start, "found %s in %s", binName, r.getIndexPath())
+				return resolution, nil
+			}
+		}
+	}
+
+	start := time.Now()
+	pkg, err := getToolsPkg(r.PkgRoot)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
+	logTiming(r.Logger, start, "parsed tools package %s", pkg.Dir)
 
-	versionedDepMatcher := regexp.MustCompile(`/v\d$`)
 	binModulePath := ""
 	for _, modulePath := range pkg.Imports {
-		if path.Base(modulePath) == binName {
-			binModulePath = modulePath
-			break
-		}
-
-		if versionedDepMatcher.MatchString(modulePath) && path.Base(path.Dir(modulePath)) == binName {
+		if getBinName(modulePath) == binName {
 			binModulePath = modulePath
 			break
 		}
 	}
 
 	if binModulePath == "" {
-		return "", fmt.Errorf("cant find bin %s in tools file", binName)
+		return nil, fmt.Errorf("cant find bin %s in tools file", binName)
 	}
 
-	mod, err := getGoMod(pkgRoot)
+	start = time.Now()
+	mod, err := getGoMod(r.PkgRoot)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
+	logTiming(r.Logger, start, "parsed %s", path.Join(r.PkgRoot, "go.mod"))
 
-	cmdPath := ""
+	resolution := &Resolution{
+		ImportPath: binModulePath,
+		BinName:    binName,
+	}
 	for _, req := range mod.Require {
 		if strings.HasPrefix(binModulePath, req.Mod.Path) {
-			cmdPath = path.Join(req.Mod.Path+"@"+req.Mod.Version, strings.ReplaceAll(binModulePath, req.Mod.Path, ""))
+			resolution.Module = req.Mod.Path
+			resolution.Version = req.Mod.Version
+			resolution.Subpackage = strings.TrimPrefix(strings.ReplaceAll(binModulePath, req.Mod.Path, ""), "/")
+			resolution.CmdPath = path.Join(req.Mod.Path+"@"+req.Mod.Version, resolution.Subpackage)
 			break
 		}
 	}
 
-	if cmdPath == "" {
-		return "", fmt.Errorf("cant find require for module %s in go.mod", binModulePath)
+	if resolution.CmdPath == "" {
+		return nil, fmt.Errorf("cant find require for module %s in go.mod", binModulePath)
+	}
+
+	sumModule, sumVersion := resolution.Module, resolution.Version
+	for _, replace := range mod.Replace {
+		if replace.Old.Path != resolution.Module || (replace.Old.Version != "" && replace.Old.Version != resolution.Version) {
+			continue
+		}
+
+		resolution.Replace = replace.New.Path
+		if replace.New.Version != "" {
+			resolution.Replace += "@" + replace.New.Version
+		}
+		sumModule, sumVersion = replace.New.Path, replace.New.Version
+	}
+
+	resolution.Sum, err = getGoSum(r.PkgRoot, sumModule, sumVersion)
+	if err != nil {
+		return nil, err
 	}
 
-	return cmdPath, nil
+	if override != "" {
+		err = r.applyOverride(resolution, override)
+		if err != nil {
+			return nil, err
+		}
+	} else if r.Sources == nil {
+		resolution.SourceDir = getModuleCacheDir(resolution.CmdPath)
+	} else if locate {
+		resolution.SourceDir, err = r.Sources.Locate(resolution.CmdPath, ioutil.Discard)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if runtime.GOOS == "windows" {
+		resolution.BinName += ".exe"
+	}
+
+	resolution.GoVersion, err = r.toolchain().Version()
+	if err != nil {
+		return nil, err
+	}
+
+	cacheCmdPath := resolution.CmdPath
+	if override != "" {
+		cacheCmdPath = path.Join(overridesDirName, cacheCmdPath)
+	}
+
+	resolution.CachePath, err = r.cache().BinPath(resolution.GoVersion, cacheCmdPath, resolution.BinName)
+	if err != nil {
+		return nil, err
+	}
+
+	if indexKey != "" {
+		r.saveIndexedResolution(indexKey, binName, resolution) //nolint // Ignore error, a read only cache is still usable.
+	}
+
+	return resolution, nil
+}
+
+// resolveLocal resolves binName like resolve without downloading any modules, returning nil for module/pkg@version
+// tools that aren't built and for @<commit> or @<version> overrides.
+func (r *Runner) resolveLocal(binName string) (*Resolution, error) {
+	if isEphemeral(binName) {
+		return r.resolveEphemeral(binName, false)
+	}
+
+	if override := r.getOverride(strings.TrimSuffix(binName, ".exe")); override != "" && !isLocalOverride(override) {
+		return nil, nil
+	}
+
+	return r.resolve(binName, false)
 }
 
 // GetCachedBin returns the path to the cached binary, building it if it doesn't exist.
 func GetCachedBin(pkgRoot, binName, cmdPath string) (string, error) {
-	// Delete source root if it was copied to a temp folder.
-	deleteSrcRoot := false
+	return (&Runner{Options: Options{PkgRoot: pkgRoot}}).getCachedBin(binName, cmdPath)
+}
 
+// Build returns the path to the tool's cached binary, building it if it doesn't exist.
+func (r *Runner) Build(binName string) (string, error) {
+	resolution, err := r.resolve(binName, false)
+	if err != nil {
+		return "", err
+	}
+
+	return r.ensureBin(resolution, nil)
+}
+
+func (r *Runner) getCachedBin(binName, cmdPath string) (string, error) {
 	if runtime.GOOS == "windows" && !strings.HasSuffix(binName, ".exe") {
 		binName += ".exe"
 	}
 
-	goVersion, err := getGoVersion()
+	goVersion, err := r.toolchain().Version()
+	if err != nil {
+		return "", err
+	}
+
+	cachedBin, err := r.cache().BinPath(goVersion, cmdPath, binName)
+	if err != nil {
+		return "", err
+	}
+
+	return r.ensureBin(&Resolution{
+		BinName:   binName,
+		Version:   getCmdPathVersion(cmdPath),
+		CmdPath:   cmdPath,
+		GoVersion: goVersion,
+		CachePath: cachedBin,
+	}, nil)
+}
+
+// ensureBin returns the cached binary for resolution, building it first if it isn't in the cache. args are passed to
+// the build hooks.
+func (r *Runner) ensureBin(resolution *Resolution, args []string) (string, error) {
+	cachedBin := resolution.CachePath
+	if resolution.Override != "" {
+		r.warnOverride(resolution)
+	}
+
+	// Local overrides are always rebuilt as their source can change between runs.
+	if r.cache().Exists(cachedBin) && (resolution.Override == "" || !isLocalOverride(resolution.Override)) {
+		if !isUntrimmedCachedBin(cachedBin) {
+			logf(r.Logger, "using cached %s", cachedBin)
+			return cachedBin, nil
+		}
+		logf(r.Logger, "rebuilding %s as it was cached without -trimpath", cachedBin)
+	}
+
+	hook := &HookContext{
+		Event:   HookPreBuild,
+		Tool:    strings.TrimSuffix(resolution.BinName, ".exe"),
+		Version: resolution.Version,
+		Args:    args,
+		BinPath: cachedBin,
+	}
+	err := r.runHooks(hook)
 	if err != nil {
 		return "", err
 	}
 
-	cachedBin, err := filepath.Abs(path.Join(pkgRoot, ".gomodrun/", goVersion, cmdPath, binName))
+	err = r.buildBin(resolution)
 	if err != nil {
 		return "", err
 	}
 
-	if _, err := os.Stat(cachedBin); os.IsNotExist(err) {
-		goPath := os.Getenv("GOPATH")
-		if goPath == "" {
-			goPath = build.Default.GOPATH
+	hook.Event = HookPostBuild
+	err = r.runHooks(hook)
+	if err != nil {
+		return "", err
+	}
+
+	return cachedBin, nil
+}
+
+// isUntrimmedBin returns true for cached binaries built without -trimpath, such as by older versions of gomodrun, which
+// are rebuilt so they match gomodrun.lock. Binaries without build settings, such as those built before go1.18, are
+// kept.
+func isUntrimmedBin(binPath string) bool {
+	info, err := buildinfo.ReadFile(binPath)
+	if err != nil || len(info.Settings) == 0 {
+		return false
+	}
+
+	for _, setting := range info.Settings {
+		if setting.Key == "-trimpath" {
+			return setting.Value != "true"
 		}
+	}
 
-		goModCmdPathVariant := ""
-		for _, r := range cmdPath {
-			if unicode.IsUpper(r) && unicode.IsLetter(r) {
-				goModCmdPathVariant += "!" + string(unicode.ToLower(r))
-			} else {
-				goModCmdPathVariant += string(r)
-			}
+	return true
+}
+
+// isUntrimmedCachedBin is isUntrimmedBin for a cached binary, which is only read until a marker next to it records
+// that it was built with -trimpath, keeping warm runs from reading the binary.
+func isUntrimmedCachedBin(binPath string) bool {
+	markerPath := getTrimmedMarkerPath(binPath)
+	if _, err := os.Stat(markerPath); err == nil {
+		return false
+	}
+
+	if isUntrimmedBin(binPath) {
+		return true
+	}
+
+	ioutil.WriteFile(markerPath, []byte{}, 0o644) //nolint // Ignore error, the binary is read again next time.
+	return false
+}
+
+// getModuleSrcRoot returns the root of the module containing srcDir, which is the first directory that's versioned
+// in the module cache or contains a go.mod.
+func getModuleSrcRoot(srcDir string) string {
+	moduleSrcRoot := srcDir
+	for {
+		if strings.Contains(path.Base(moduleSrcRoot), "@") {
+			return moduleSrcRoot
 		}
 
-		moduleBinSrcPath := path.Join(goPath, "pkg", "mod", goModCmdPathVariant)
-		if _, err := os.Stat(moduleBinSrcPath); os.IsNotExist(err) {
-			download := exec.Command("go", "mod", "download")
-			download.Dir = pkgRoot
-			err = download.Run()
-			if err != nil {
-				return "", err
-			}
+		if _, err := os.Stat(path.Join(moduleSrcRoot, "go.mod")); err == nil {
+			return moduleSrcRoot
 		}
 
-		moduleSrcRoot := moduleBinSrcPath
-		for {
-			if strings.Contains(path.Base(moduleSrcRoot), "@") {
-				break
-			}
-			moduleSrcRoot = path.Dir(moduleSrcRoot)
+		if path.Dir(moduleSrcRoot) == moduleSrcRoot {
+			return srcDir
 		}
+		moduleSrcRoot = path.Dir(moduleSrcRoot)
+	}
+}
 
-		if _, err := os.Stat(path.Join(moduleSrcRoot, "go.mod")); os.IsNotExist(err) {
-			pkgName := strings.Split(strings.Split(strings.ReplaceAll(moduleSrcRoot, "!", ""), "pkg/mod/")[1], "@")[0]
-			tempDir, err := ioutil.TempDir("", binName)
-			if err != nil {
-				return "", err
-			}
+// buildBin builds resolution in to its cache path, locating its source and logging the build next to the binary.
+func (r *Runner) buildBin(resolution *Resolution) error {
+	binName, cmdPath, cachedBin, goVersion := resolution.BinName, resolution.CmdPath, resolution.CachePath, resolution.GoVersion
+	logf(r.Logger, "%s is not cached, building %s", binName, cmdPath)
+	if r.Progress != nil {
+		fmt.Fprintf(r.Progress, "building %s@%s with %s…\n", strings.TrimSuffix(binName, ".exe"), getCmdPathVersion(cmdPath), goVersion)
+	}
 
-			err = copy.Copy(moduleSrcRoot, tempDir)
-			if err != nil {
-				return "", err
-			}
+	err := os.MkdirAll(path.Dir(cachedBin), os.ModePerm)
+	if err != nil {
+		return err
+	}
 
-			err = os.Chmod(tempDir, 0o777)
-			if err != nil {
-				return "", err
-			}
+	// A rebuilt binary may no longer match what the markers recorded for the old one.
+	for _, markerPath := range []string{getCompletionMarkerPath(cachedBin), getTrimmedMarkerPath(cachedBin)} {
+		err = os.Remove(markerPath)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
 
-			cmd := exec.Command("go", "mod", "init", pkgName)
-			cmd.Dir = tempDir
-			output, err := cmd.CombinedOutput()
-			if err != nil {
-				return "", fmt.Errorf("initializing modules %s go.mod failed: %s", pkgName, output)
-			}
+	logPath := getBuildLogPath(cachedBin)
+	logFile, err := os.Create(logPath)
+	if err != nil {
+		return err
+	}
+	defer logFile.Close()
 
-			moduleBinSrcPath = strings.ReplaceAll(moduleBinSrcPath, moduleSrcRoot, tempDir)
-			deleteSrcRoot = true
+	output := &bytes.Buffer{}
+	writers := []io.Writer{logFile, output}
+	if r.BuildOutput != nil {
+		writers = append(writers, r.BuildOutput)
+	}
+	writer := io.MultiWriter(writers...)
+
+	start := time.Now()
+	moduleBinSrcPath := resolution.SourceDir
+	if resolution.Override == "" && !resolution.Ephemeral {
+		moduleBinSrcPath, err = r.sources().Locate(cmdPath, writer)
+		if err != nil {
+			return fmt.Errorf("locating source for %s failed, full log in %s:\n%s", binName, logPath, getLogTail(output.String()))
 		}
+	}
+	logTiming(r.Logger, start, "found source %s", moduleBinSrcPath)
+
+	moduleSrcRoot := getModuleSrcRoot(moduleBinSrcPath)
+	if _, err := os.Stat(path.Join(moduleSrcRoot, "go.mod")); os.IsNotExist(err) {
+		start := time.Now()
+		pkgName := strings.Split(cmdPath, "@")[0]
+		tempDir, err := ioutil.TempDir("", binName)
+		if err != nil {
+			return err
+		}
+		defer os.RemoveAll(tempDir) //nolint // Ignore error, not interested if it fails.
 
-		err := os.MkdirAll(path.Dir(cachedBin), os.ModePerm)
+		err = copy.Copy(moduleSrcRoot, tempDir)
 		if err != nil {
-			return "", err
+			return err
 		}
 
-		cmd := exec.Command("go", "build", "-o", cachedBin)
-		cmd.Dir = moduleBinSrcPath
-		output, err := cmd.CombinedOutput()
+		err = os.Chmod(tempDir, 0o777)
 		if err != nil {
-			return "", fmt.Errorf("building %s failed: %s", binName, output)
+			return err
 		}
 
-		if deleteSrcRoot {
-			os.RemoveAll(moduleBinSrcPath) //nolint // Ignore error, not interested if it fails.
+		err = runToolchain(r.toolchain(), tempDir, writer, "mod", "init", pkgName)
+		if err != nil {
+			return fmt.Errorf("initializing modules %s go.mod failed, full log in %s:\n%s", pkgName, logPath, getLogTail(output.String()))
 		}
+
+		moduleBinSrcPath = strings.Replace(moduleBinSrcPath, moduleSrcRoot, tempDir, 1)
+		logTiming(r.Logger, start, "copied %s without a go.mod to %s", moduleSrcRoot, tempDir)
 	}
 
-	return cachedBin, nil
+	start = time.Now()
+	err = runToolchain(r.toolchain(), moduleBinSrcPath, writer, "build", "-trimpath", "-o", cachedBin)
+	if err != nil {
+		return fmt.Errorf("building %s failed, full log in %s:\n%s", binName, logPath, getLogTail(output.String()))
+	}
+	logTiming(r.Logger, start, "built %s", cachedBin)
+
+	return nil
 }
 
-// Run executes your binary.
+// Run executes your binary. binName can also be module/pkg@version to run a tool that isn't in the tools file,
+// similar to `go run module/pkg@version`.
 func Run(binName string, args []string, options *Options) (int, error) {
-	var err error
-	pkgRoot := options.PkgRoot
+	runner, err := NewRunner(options)
+	if err != nil {
+		return -1, err
+	}
 
-	if pkgRoot == "" {
-		pkgRoot, err = GetPkgRoot()
-		if err != nil {
-			return -1, err
-		}
+	return runner.Run(binName, args)
+}
+
+// Run executes your binary, building it first if it isn't cached.
+func (r *Runner) Run(binName string, args []string) (int, error) {
+	resolution, err := r.resolve(binName, false)
+	if err != nil {
+		return -1, err
+	}
+
+	return r.runResolved(resolution, args)
+}
+
+// runResolved runs the tool described by resolution, building it first if it isn't cached.
+func (r *Runner) runResolved(resolution *Resolution, args []string) (int, error) {
+	cachedBin, err := r.ensureBin(resolution, args)
+	if err != nil {
+		return -1, err
 	}
 
-	cmdPath, err := GetCommandVersionedPkgPath(pkgRoot, binName)
+	return r.runBin(resolution, cachedBin, args)
+}
+
+// runBin runs cachedBin, the built binary of the tool described by resolution, along with the run hooks.
+func (r *Runner) runBin(resolution *Resolution, cachedBin string, args []string) (int, error) {
+	r.cache().MarkUsed(cachedBin)
+
+	env, err := r.getToolEnv(resolution.BinName)
 	if err != nil {
 		return -1, err
 	}
 
-	cachedBin, err := GetCachedBin(pkgRoot, binName, cmdPath)
+	hook := &HookContext{
+		Event:   HookPreRun,
+		Tool:    strings.TrimSuffix(resolution.BinName, ".exe"),
+		Version: resolution.Version,
+		Args:    args,
+		BinPath: cachedBin,
+	}
+	err = r.runHooks(hook)
 	if err != nil {
 		return -1, err
 	}
 
 	cmd := exec.Command(cachedBin, args...)
-	cmd.Stdin = options.Stdin
-	cmd.Stderr = options.Stderr
-	cmd.Stdout = options.Stdout
-	cmd.Env = options.Env
+	cmd.Stdin = r.Stdin
+	cmd.Stderr = r.Stderr
+	cmd.Stdout = r.Stdout
+	cmd.Env = env
+	cmd.Dir = r.Dir
+
+	start := time.Now()
+	exitCode := 0
 	err = cmd.Run()
 	if exiterr, ok := err.(*exec.ExitError); ok {
 		if status, ok := exiterr.Sys().(syscall.WaitStatus); ok {
-			return status.ExitStatus(), nil
+			exitCode = status.ExitStatus()
 		}
+	} else if err != nil {
+		return -1, err
+	}
+	logTiming(r.Logger, start, "ran %s with exit code %d", resolution.BinName, exitCode)
+
+	hook.Event = HookPostRun
+	hook.ExitCode = exitCode
+	err = r.runHooks(hook)
+	if err != nil {
+		return exitCode, err
 	}
 
-	return 0, nil
+	return exitCode, nil
 }
@@ -0,0 +1,122 @@
+// Package gomodrun is the forgotten go tool that executes and caches binaries included in go.mod files.
+// This makes it easy to version cli tools in your projects such as `golangci-lint`
+// and `ginkgo` that are versioned locked to what you specify in `go.mod`.
+// Binaries are cached by go version and package version.
+package gomodrun
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"golang.org/x/mod/module"
+	"golang.org/x/mod/semver"
+)
+
+var errModuleNotFound = errors.New("module not found")
+
+// errProxyDirect is returned by getModuleVersions when GOPROXY falls back to direct, which only the go command can
+// look up.
+var errProxyDirect = errors.New("module lookup falls back to direct")
+
+// proxyClient fetches from GOPROXY, timing out so a stalled proxy doesn't hang outdated and upgrade.
+var proxyClient = &http.Client{Timeout: 30 * time.Second}
+
+// getProxyFile fetches a file from a single GOPROXY entry, supporting http(s):// and file:// proxies.
+func getProxyFile(proxy, filePath string) ([]byte, error) {
+	if strings.HasPrefix(proxy, "file://") {
+		proxyURL, err := url.Parse(proxy)
+		if err != nil {
+			return nil, err
+		}
+
+		data, err := ioutil.ReadFile(filepath.Join(filepath.FromSlash(proxyURL.Path), filepath.FromSlash(filePath)))
+		if os.IsNotExist(err) {
+			return nil, errModuleNotFound
+		}
+		return data, err
+	}
+
+	res, err := proxyClient.Get(strings.TrimSuffix(proxy, "/") + "/" + filePath) //nolint:gosec // Proxy URLs come from GOPROXY.
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == http.StatusNotFound || res.StatusCode == http.StatusGone {
+		return nil, errModuleNotFound
+	}
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("reading %s from %s failed: %s", filePath, proxy, res.Status)
+	}
+
+	return ioutil.ReadAll(res.Body)
+}
+
+// getModuleVersions returns all versions of modulePath listed by GOPROXY, following the go command's rules for
+// falling back between proxies. Returns errModuleNotFound when no proxy has the module, or errProxyDirect when the
+// lookup reaches a direct entry.
+func getModuleVersions(goProxy, modulePath string) ([]string, error) {
+	escapedPath, err := module.EscapePath(modulePath)
+	if err != nil {
+		return nil, err
+	}
+
+	proxies := goProxy
+	checked := false
+	err = errModuleNotFound
+	for goProxy != "" {
+		proxy := goProxy
+		fallbackOnError := false
+		if idx := strings.IndexAny(goProxy, ",|"); idx != -1 {
+			proxy = goProxy[:idx]
+			fallbackOnError = goProxy[idx] == '|'
+			goProxy = goProxy[idx+1:]
+		} else {
+			goProxy = ""
+		}
+
+		if proxy == "off" {
+			return nil, fmt.Errorf("module lookup disabled by GOPROXY=off")
+		}
+
+		if proxy == "direct" {
+			return nil, errProxyDirect
+		}
+
+		if proxy == "" {
+			continue
+		}
+
+		checked = true
+		var data []byte
+		data, err = getProxyFile(proxy, escapedPath+"/@v/list")
+		if err == nil {
+			versions := []string{}
+			for _, version := range strings.Fields(string(data)) {
+				if semver.IsValid(version) {
+					versions = append(versions, version)
+				}
+			}
+			semver.Sort(versions)
+			return versions, nil
+		}
+
+		if err != errModuleNotFound && !fallbackOnError {
+			return nil, err
+		}
+	}
+
+	if !checked {
+		return nil, fmt.Errorf("GOPROXY=%s has no proxy to list module versions from", proxies)
+	}
+
+	return nil, err
+}
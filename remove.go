@@ -0,0 +1,171 @@
+// Package gomodrun is the forgotten go tool that executes and caches binaries included in go.mod files.
+// This makes it easy to version cli tools in your projects such as `golangci-lint`
+// and `ginkgo` that are versioned locked to what you specify in `go.mod`.
+// Binaries are cached by go version and package version.
+package gomodrun
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+)
+
+// getToolImportPath returns the tools file import for tool, which can be a package path or binary name.
+func getToolImportPath(pkgRoot, tool string) (string, error) {
+	pkg, err := getToolsPkg(pkgRoot)
+	if err != nil {
+		return "", err
+	}
+
+	binName := strings.TrimSuffix(tool, ".exe")
+	for _, modulePath := range pkg.Imports {
+		if modulePath == tool || getBinName(modulePath) == binName {
+			return modulePath, nil
+		}
+	}
+
+	return "", fmt.Errorf("cant find bin %s in tools file", tool)
+}
+
+// removeCachedTool removes every cached binary, link and shim for importPath, returning the removed paths.
+func removeCachedTool(pkgRoot, importPath string) ([]string, error) {
+	removed := []string{}
+	gmrRoot := path.Join(pkgRoot, ".gomodrun")
+	if _, err := os.Stat(gmrRoot); os.IsNotExist(err) {
+		return removed, nil
+	}
+
+	entries, err := getCacheEntries(gmrRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	binName := getBinName(importPath)
+	for _, entry := range entries {
+		if strings.TrimSuffix(entry.tool, ".exe") != path.Join(importPath, binName) {
+			continue
+		}
+
+		err = removeCacheEntry(entry.path)
+		if err != nil {
+			return nil, err
+		}
+		removed = append(removed, entry.path)
+	}
+
+	for _, linkPath := range []string{
+		path.Join(gmrRoot, binDirName, binName),
+		path.Join(gmrRoot, binDirName, binName+".exe"),
+		path.Join(gmrRoot, shimsDirName, binName),
+		path.Join(gmrRoot, shimsDirName, binName+".cmd"),
+	} {
+		if _, err = os.Lstat(linkPath); os.IsNotExist(err) {
+			continue
+		}
+
+		err = os.Remove(linkPath)
+		if err != nil {
+			return nil, err
+		}
+		removed = append(removed, linkPath)
+	}
+
+	// The index is stale once go.mod and the tools file change.
+	err = os.Remove(path.Join(gmrRoot, indexFileName))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	return removed, cleanEmptyDirectory(gmrRoot)
+}
+
+// Remove drops a tool by removing its import from the tools file, running `go mod tidy` to drop the unused require
+// from go.mod, and deleting its binaries from .gomodrun. tool can be a package path or binary name.
+func Remove(pkgRoot, tool string) (*ToolChange, error) {
+	var err error
+	if pkgRoot == "" {
+		pkgRoot, err = GetPkgRoot()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	change := &ToolChange{
+		CachedBins: []string{},
+	}
+
+	change.ImportPath, err = getToolImportPath(pkgRoot, tool)
+	if err != nil {
+		return nil, err
+	}
+
+	toolsFiles, err := getToolsFiles(pkgRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	mod, err := getGoMod(pkgRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	if req := getRequire(mod, change.ImportPath); req != nil {
+		change.Module = req.Mod.Path
+		change.Version = req.Mod.Version
+	}
+
+	snapshot, err := getModSnapshot(pkgRoot, toolsFiles...)
+	if err != nil {
+		return nil, err
+	}
+
+	// A tool can be imported from more than one tools file, remove it from all of them.
+	for _, toolsFile := range toolsFiles {
+		original := snapshot[toolsFile]
+		found, err := hasToolImport(original, change.ImportPath)
+		if err != nil {
+			return nil, err
+		}
+		if !found {
+			continue
+		}
+
+		src, err := removeToolImport(original, change.ImportPath)
+		if err != nil {
+			snapshot.restore()
+			return nil, err
+		}
+
+		err = ioutil.WriteFile(toolsFile, src, 0o644) //nolint:gosec // Source files are world readable.
+		if err != nil {
+			snapshot.restore()
+			return nil, err
+		}
+
+		if change.ToolsFile == "" {
+			change.ToolsFile = toolsFile
+		}
+	}
+
+	err = runGo(pkgRoot, "mod", "tidy")
+	if err != nil {
+		// Restore the tools files, go.mod and go.sum so a failed remove leaves the project untouched.
+		snapshot.restore()
+		return nil, err
+	}
+
+	mod, err = getGoMod(pkgRoot)
+	if err != nil {
+		return nil, err
+	}
+	change.ModuleRemoved = change.Module != "" && getRequire(mod, change.ImportPath) == nil
+
+	change.CachedBins, err = removeCachedTool(pkgRoot, change.ImportPath)
+	if err != nil {
+		return nil, err
+	}
+
+	return change, nil
+}
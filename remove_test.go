@@ -0,0 +1,118 @@
+package gomodrun
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"runtime"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/otiai10/copy"
+)
+
+var _ = Describe("remove", func() {
+	var tempDir string
+	var goProxy string
+
+	BeforeEach(func() {
+		// go-hello-world-test is already in the module cache as it's required by gomodrun's go.mod.
+		goProxy = os.Getenv("GOPROXY")
+		err := os.Setenv("GOPROXY", "off")
+		if err != nil {
+			panic(err)
+		}
+
+		tempDir, err = ioutil.TempDir(os.TempDir(), "gomodrun-remove")
+		if err != nil {
+			panic(err)
+		}
+
+		//nolint:dogsled // Test file, don't need any of the extra values
+		_, filename, _, _ := runtime.Caller(0)
+		err = copy.Copy(path.Join(path.Dir(filename), "./tests/alternative-tools-dir"), tempDir)
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	AfterEach(func() {
+		err := os.Setenv("GOPROXY", goProxy)
+		if err != nil {
+			panic(err)
+		}
+
+		err = os.RemoveAll(tempDir)
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	It("should remove the import, require and cached binaries", func() {
+		binDir, err := CreateBinDir(tempDir)
+		Expect(err).To(BeNil())
+
+		change, err := Remove(tempDir, "hello-world")
+		Expect(err).To(BeNil())
+		Expect(change.ImportPath).To(Equal("github.com/dustinblackman/go-hello-world-test/hello-world"))
+		Expect(change.ToolsFile).To(Equal(path.Join(tempDir, "go.tools.go")))
+		Expect(change.Module).To(Equal("github.com/dustinblackman/go-hello-world-test"))
+		Expect(change.Version).To(Equal("v0.0.2"))
+		Expect(change.ModuleRemoved).To(BeTrue())
+		Expect(change.CachedBins).To(HaveLen(2))
+
+		content, err := ioutil.ReadFile(change.ToolsFile)
+		Expect(err).To(BeNil())
+		Expect(string(content)).ToNot(ContainSubstring("hello-world"))
+
+		mod, err := getGoMod(tempDir)
+		Expect(err).To(BeNil())
+		Expect(mod.Require).To(BeEmpty())
+
+		_, err = os.Lstat(path.Join(binDir, "hello-world"))
+		Expect(os.IsNotExist(err)).To(BeTrue())
+
+		_, err = os.Stat(path.Join(tempDir, ".gomodrun"))
+		Expect(os.IsNotExist(err)).To(BeTrue())
+	})
+
+	It("should return an error when the tool is not in the tools file", func() {
+		change, err := Remove(tempDir, "not-real")
+		Expect(err).ToNot(BeNil())
+		Expect(change).To(BeNil())
+	})
+
+	It("should remove the import from every tools file", func() {
+		secondFile := path.Join(tempDir, "z.tools.go")
+		err := ioutil.WriteFile(secondFile, []byte("// +build tools\n\npackage gomodrun\n\nimport (\n\t_ \"github.com/dustinblackman/go-hello-world-test/hello-world\"\n)\n"), 0600)
+		Expect(err).To(BeNil())
+
+		change, err := Remove(tempDir, "hello-world")
+		Expect(err).To(BeNil())
+		Expect(change.ToolsFile).To(Equal(path.Join(tempDir, "go.tools.go")))
+
+		for _, toolsFile := range []string{path.Join(tempDir, "go.tools.go"), secondFile} {
+			content, err := ioutil.ReadFile(toolsFile)
+			Expect(err).To(BeNil())
+			Expect(string(content)).ToNot(ContainSubstring("hello-world"))
+		}
+	})
+
+	It("should remove tools imported from a second tools file", func() {
+		err := ioutil.WriteFile(path.Join(tempDir, "go.tools.go"), []byte("// +build tools\n\npackage gomodrun\n"), 0600)
+		Expect(err).To(BeNil())
+		secondFile := path.Join(tempDir, "z.tools.go")
+		err = ioutil.WriteFile(secondFile, []byte("// +build tools\n\npackage gomodrun\n\nimport (\n\t_ \"github.com/dustinblackman/go-hello-world-test/hello-world\"\n)\n"), 0600)
+		Expect(err).To(BeNil())
+
+		change, err := Remove(tempDir, "hello-world")
+		Expect(err).To(BeNil())
+		Expect(change.ToolsFile).To(Equal(secondFile))
+		Expect(change.ModuleRemoved).To(BeTrue())
+
+		content, err := ioutil.ReadFile(secondFile)
+		Expect(err).To(BeNil())
+		Expect(string(content)).ToNot(ContainSubstring("hello-world"))
+	})
+})
@@ -0,0 +1,280 @@
+// Package gomodrun is the forgotten go tool that executes and caches binaries included in go.mod files.
+// This makes it easy to version cli tools in your projects such as `golangci-lint`
+// and `ginkgo` that are versioned locked to what you specify in `go.mod`.
+// Binaries are cached by go version and package version.
+package gomodrun
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go/build"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// Toolchain runs the go command.
+type Toolchain interface {
+	// Version returns the go version, such as go1.22.3.
+	Version() (string, error)
+	// Run runs go with args in dir, writing its output to output.
+	Run(dir string, output io.Writer, args ...string) error
+}
+
+// SourceLocator finds the source of a tool's package.
+type SourceLocator interface {
+	// Locate returns the source directory of cmdPath, such as github.com/org/tool@v1.0.0/cmd/tool, writing the
+	// output of any downloads to output.
+	Locate(cmdPath string, output io.Writer) (string, error)
+}
+
+// CacheStore stores built binaries.
+type CacheStore interface {
+	// BinPath returns where the binary for cmdPath built with goVersion is stored.
+	BinPath(goVersion, cmdPath, binName string) (string, error)
+	// Exists reports whether a binary has been stored at binPath.
+	Exists(binPath string) bool
+	// MarkUsed records that the binary at binPath was just used.
+	MarkUsed(binPath string)
+}
+
+// Runner resolves, builds and runs the tools of a single package. Fields left empty use the defaults of the go
+// command on PATH, the module cache and .gomodrun in the package root.
+type Runner struct {
+	Options                 // Stdio, environment, package root and logging used for builds and tools.
+	CacheDir  string        // Directory binaries are cached in. Defaults to .gomodrun in PkgRoot.
+	GoBin     string        // Path to the go binary. Defaults to go on PATH.
+	GoEnv     []string      // Environment variables passed to go. Defaults to the current environment.
+	Toolchain Toolchain     // Runs go commands. Defaults to GoBin with GoEnv.
+	Sources   SourceLocator // Finds tool sources. Defaults to the module cache, downloading modules with Toolchain.
+	Cache     CacheStore    // Stores built binaries. Defaults to CacheDir.
+	NoIndex   bool          // Resolve tools from scratch instead of using the resolution index in CacheDir.
+
+	hooks  map[HookEvent][]HookFunc
+	config *runnerConfig
+}
+
+// runnerConfig holds .gomodrun.yml once it's been loaded for a Runner, so every hook and tool run reads it once.
+type runnerConfig struct {
+	once   sync.Once
+	config *Config
+	err    error
+}
+
+// NewRunner returns a Runner using options, finding the package root when options.PkgRoot is empty.
+func NewRunner(options *Options) (*Runner, error) {
+	runner := &Runner{Options: *options, config: &runnerConfig{}}
+	if runner.PkgRoot == "" {
+		var err error
+		start := time.Now()
+		runner.PkgRoot, err = GetPkgRoot()
+		if err != nil {
+			return nil, err
+		}
+		logTiming(runner.Logger, start, "found package root %s", runner.PkgRoot)
+	}
+
+	return runner, nil
+}
+
+func (r *Runner) toolchain() Toolchain {
+	if r.Toolchain != nil {
+		return r.Toolchain
+	}
+
+	goBin := r.GoBin
+	if goBin == "" {
+		goBin = "go"
+	}
+
+	return &goToolchain{
+		path: goBin,
+		env:  r.GoEnv,
+	}
+}
+
+func (r *Runner) sources() SourceLocator {
+	if r.Sources != nil {
+		return r.Sources
+	}
+
+	return &moduleCacheSource{
+		toolchain: r.toolchain(),
+		pkgRoot:   r.PkgRoot,
+	}
+}
+
+func (r *Runner) cacheDir() string {
+	if r.CacheDir != "" {
+		return r.CacheDir
+	}
+
+	return path.Join(r.PkgRoot, ".gomodrun")
+}
+
+// loadConfig returns .gomodrun.yml from the package root, loading it on first use.
+func (r *Runner) loadConfig() (*Config, error) {
+	if r.config == nil {
+		return LoadConfig(r.PkgRoot)
+	}
+
+	r.config.once.Do(func() {
+		r.config.config, r.config.err = LoadConfig(r.PkgRoot)
+	})
+
+	return r.config.config, r.config.err
+}
+
+func (r *Runner) cache() CacheStore {
+	if r.Cache != nil {
+		return r.Cache
+	}
+
+	return &dirCache{dir: r.cacheDir()}
+}
+
+// runToolchain runs a go command through toolchain, writing the command and its output to output.
+func runToolchain(toolchain Toolchain, dir string, output io.Writer, args ...string) error {
+	fmt.Fprintf(output, "$ go %s\n", strings.Join(args, " "))
+	return toolchain.Run(dir, output, args...)
+}
+
+// runGo runs go with args in the package root through the toolchain, including its output in the error when it fails.
+func (r *Runner) runGo(args ...string) error {
+	output := &bytes.Buffer{}
+	err := r.toolchain().Run(r.PkgRoot, output, args...)
+	if err != nil {
+		return fmt.Errorf("go %s failed: %s", strings.Join(args, " "), output)
+	}
+
+	return nil
+}
+
+// goToolchain runs a go binary.
+type goToolchain struct {
+	path string
+	env  []string
+}
+
+func (t *goToolchain) Version() (string, error) {
+	cmd := exec.Command(t.path, "version")
+	cmd.Env = t.env
+	goVersionOutput, err := cmd.Output()
+	if err != nil {
+		return "", err
+	}
+
+	return strings.Split(string(goVersionOutput), " ")[2], nil
+}
+
+func (t *goToolchain) Run(dir string, output io.Writer, args ...string) error {
+	cmd := exec.Command(t.path, args...)
+	cmd.Dir = dir
+	cmd.Env = t.env
+	cmd.Stdout = output
+	cmd.Stderr = output
+
+	return cmd.Run()
+}
+
+// moduleCacheSource locates tool sources in the module cache, downloading the package's modules if they're missing.
+type moduleCacheSource struct {
+	toolchain Toolchain
+	pkgRoot   string
+}
+
+func (s *moduleCacheSource) Locate(cmdPath string, output io.Writer) (string, error) {
+	moduleBinSrcPath := getModuleCacheDir(cmdPath)
+	if _, err := os.Stat(moduleBinSrcPath); os.IsNotExist(err) {
+		err = runToolchain(s.toolchain, s.pkgRoot, output, "mod", "download")
+		if err != nil {
+			return "", err
+		}
+	}
+
+	return moduleBinSrcPath, nil
+}
+
+// getModuleCacheDir returns where cmdPath is extracted in the module cache, escaping upper case letters as go does.
+func getModuleCacheDir(cmdPath string) string {
+	goPath := os.Getenv("GOPATH")
+	if goPath == "" {
+		goPath = build.Default.GOPATH
+	}
+
+	goModCmdPathVariant := ""
+	for _, r := range cmdPath {
+		if unicode.IsUpper(r) && unicode.IsLetter(r) {
+			goModCmdPathVariant += "!" + string(unicode.ToLower(r))
+		} else {
+			goModCmdPathVariant += string(r)
+		}
+	}
+
+	return path.Join(goPath, "pkg", "mod", goModCmdPathVariant)
+}
+
+// moduleDownload is the output of `go mod download -json`.
+type moduleDownload struct {
+	Path    string // Module path.
+	Version string // Version the query resolved to.
+	Dir     string // Directory the module was extracted to.
+	Sum     string // Module hash for go.sum.
+	Error   string // Error downloading the module.
+}
+
+// downloadModule downloads query, such as example.com/tool@v1.0.0, in to the module cache. It runs outside of the
+// package so go.mod and go.sum are left untouched.
+func (r *Runner) downloadModule(query string) (*moduleDownload, error) {
+	tempDir, err := ioutil.TempDir("", "gomodrun-download")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tempDir) //nolint // Ignore error, not interested if it fails.
+
+	output := &bytes.Buffer{}
+	runErr := r.toolchain().Run(tempDir, output, "mod", "download", "-json", query)
+
+	download := &moduleDownload{}
+	start := bytes.IndexByte(output.Bytes(), '{')
+	if start == -1 || json.NewDecoder(bytes.NewReader(output.Bytes()[start:])).Decode(download) != nil {
+		return nil, errors.New(strings.TrimSpace(output.String()))
+	}
+
+	if download.Error != "" {
+		return nil, errors.New(download.Error)
+	}
+
+	if runErr != nil {
+		return nil, errors.New(strings.TrimSpace(output.String()))
+	}
+
+	return download, nil
+}
+
+// dirCache stores binaries in a directory by go version and module version.
+type dirCache struct {
+	dir string
+}
+
+func (c *dirCache) BinPath(goVersion, cmdPath, binName string) (string, error) {
+	return filepath.Abs(path.Join(c.dir, goVersion, cmdPath, binName))
+}
+
+func (c *dirCache) Exists(binPath string) bool {
+	_, err := os.Stat(binPath)
+	return !os.IsNotExist(err)
+}
+
+func (c *dirCache) MarkUsed(binPath string) {
+	markBinUsed(binPath)
+}
@@ -0,0 +1,195 @@
+package gomodrun
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"sync"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type fakeToolchain struct {
+	downloads map[string]string
+	script    string
+	calls     []string
+	dirs      []string
+	versions  int
+}
+
+func (t *fakeToolchain) Version() (string, error) {
+	t.versions++
+	return "go9.9.9", nil
+}
+
+func (t *fakeToolchain) Run(dir string, output io.Writer, args ...string) error {
+	t.calls = append(t.calls, strings.Join(args, " "))
+	t.dirs = append(t.dirs, dir)
+	if args[0] == "mod" && args[1] == "download" {
+		download, ok := t.downloads[args[len(args)-1]]
+		if !ok {
+			fmt.Fprintf(output, `{"Error": "%s: not found"}`, args[len(args)-1])
+			return errors.New("exit status 1")
+		}
+		_, err := io.WriteString(output, download)
+		return err
+	}
+
+	if args[0] == "build" {
+		script := t.script
+		if script == "" {
+			script = "exit 3"
+		}
+		return ioutil.WriteFile(args[len(args)-1], []byte("#!/bin/sh\n"+script+"\n"), 0700)
+	}
+
+	return nil
+}
+
+type fakeSources struct {
+	dir     string
+	located []string
+}
+
+func (s *fakeSources) Locate(cmdPath string, output io.Writer) (string, error) {
+	s.located = append(s.located, cmdPath)
+	return s.dir, nil
+}
+
+type fakeCache struct {
+	dir   string
+	used  []string
+	mutex sync.Mutex
+}
+
+func (c *fakeCache) BinPath(goVersion, cmdPath, binName string) (string, error) {
+	return path.Join(c.dir, goVersion, binName), nil
+}
+
+func (c *fakeCache) Exists(binPath string) bool {
+	_, err := os.Stat(binPath)
+	return err == nil
+}
+
+func (c *fakeCache) MarkUsed(binPath string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.used = append(c.used, binPath)
+}
+
+var _ = Describe("runner", func() {
+	var tempDir string
+	var toolchain *fakeToolchain
+	var sources *fakeSources
+	var cache *fakeCache
+	var runner *Runner
+
+	BeforeEach(func() {
+		tempDir = createProjectFixture()
+		srcDir := path.Join(tempDir, "src")
+		err := os.MkdirAll(srcDir, 0750)
+		Expect(err).To(BeNil())
+		err = ioutil.WriteFile(path.Join(srcDir, "go.mod"), []byte("module example.com/hello-world\n"), 0600)
+		Expect(err).To(BeNil())
+
+		toolchain = &fakeToolchain{}
+		sources = &fakeSources{dir: srcDir}
+		cache = &fakeCache{dir: path.Join(tempDir, "cache")}
+		runner, err = NewRunner(&Options{PkgRoot: tempDir})
+		Expect(err).To(BeNil())
+		runner.Toolchain = toolchain
+		runner.Sources = sources
+		runner.Cache = cache
+	})
+
+	AfterEach(func() {
+		err := os.RemoveAll(tempDir)
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	It("should list and resolve tools", func() {
+		binNames, err := runner.List()
+		Expect(err).To(BeNil())
+		Expect(binNames).To(Equal([]string{"hello-world"}))
+
+		resolution, err := runner.Resolve("hello-world")
+		Expect(err).To(BeNil())
+		Expect(resolution).To(Equal(&Resolution{
+			ImportPath: "github.com/dustinblackman/go-hello-world-test/hello-world",
+			Module:     "github.com/dustinblackman/go-hello-world-test",
+			Version:    "v0.0.2",
+			Subpackage: "hello-world",
+			Sum:        "h1:DcAbKiyeohJ/c/3m5c7h3tQGKA8q7J9eahZpAjo3ZZs=",
+			CmdPath:    "github.com/dustinblackman/go-hello-world-test@v0.0.2/hello-world",
+			SourceDir:  path.Join(tempDir, "src"),
+			BinName:    "hello-world",
+			GoVersion:  "go9.9.9",
+			CachePath:  path.Join(tempDir, "cache", "go9.9.9", "hello-world"),
+		}))
+		Expect(toolchain.calls).To(BeEmpty())
+	})
+
+	It("should resolve replacements", func() {
+		goMod := path.Join(tempDir, "go.mod")
+		data, err := ioutil.ReadFile(goMod)
+		Expect(err).To(BeNil())
+		err = ioutil.WriteFile(goMod, append(data, []byte("\nreplace github.com/dustinblackman/go-hello-world-test => ../fork\n")...), 0600)
+		Expect(err).To(BeNil())
+
+		resolution, err := runner.Resolve("hello-world")
+		Expect(err).To(BeNil())
+		Expect(resolution.Replace).To(Equal("../fork"))
+		Expect(resolution.Sum).To(BeEmpty())
+	})
+
+	It("should resolve sources in the module cache without downloading", func() {
+		resolution, err := Resolve("hello-world", &Options{PkgRoot: tempDir})
+		Expect(err).To(BeNil())
+		Expect(resolution.SourceDir).To(HaveSuffix(path.Join("pkg", "mod", "github.com/dustinblackman/go-hello-world-test@v0.0.2/hello-world")))
+		Expect(resolution.CachePath).To(HavePrefix(path.Join(tempDir, ".gomodrun")))
+	})
+
+	It("should build with the toolchain, source locator and cache store", func() {
+		cachedBin, err := runner.Build("hello-world")
+		Expect(err).To(BeNil())
+		Expect(cachedBin).To(Equal(path.Join(tempDir, "cache", "go9.9.9", "hello-world")))
+		Expect(sources.located).To(Equal([]string{"github.com/dustinblackman/go-hello-world-test@v0.0.2/hello-world"}))
+		Expect(toolchain.calls).To(Equal([]string{"build -trimpath -o " + cachedBin}))
+		Expect(toolchain.dirs).To(Equal([]string{path.Join(tempDir, "src")}))
+
+		_, err = runner.Build("hello-world")
+		Expect(err).To(BeNil())
+		Expect(toolchain.calls).To(HaveLen(1))
+	})
+
+	It("should run the built binary and mark it used", func() {
+		exitCode, err := runner.Run("hello-world", []string{})
+		Expect(err).To(BeNil())
+		Expect(exitCode).To(Equal(3))
+		Expect(cache.used).To(Equal([]string{path.Join(tempDir, "cache", "go9.9.9", "hello-world")}))
+	})
+
+	It("should return an error when the binary can't be started", func() {
+		runner.Dir = path.Join(tempDir, "missing")
+		exitCode, err := runner.Run("hello-world", []string{})
+		Expect(err).ToNot(BeNil())
+		Expect(exitCode).To(Equal(-1))
+	})
+
+	It("should tidy the cache directory", func() {
+		addTidyBins(tempDir, "go9.9.9")
+		runner.CacheDir = path.Join(tempDir, ".gomodrun")
+		report, err := runner.Tidy(&TidyOptions{DryRun: true})
+		Expect(err).To(BeNil())
+		Expect(report.Kept).To(HaveLen(1))
+		Expect(report.Kept[0].Path).To(HavePrefix(path.Join(tempDir, ".gomodrun", "go9.9.9")))
+		Expect(report.Removed).To(ContainElement(HaveField("Path", path.Join(tempDir, ".gomodrun", "go1.0.1"))))
+	})
+})
@@ -0,0 +1,299 @@
+// Package gomodrun is the forgotten go tool that executes and caches binaries included in go.mod files.
+// This makes it easy to version cli tools in your projects such as `golangci-lint`
+// and `ginkgo` that are versioned locked to what you specify in `go.mod`.
+// Binaries are cached by go version and package version.
+package gomodrun
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+// shimsDirName is the directory in .gomodrun containing shims for every tool in the tools file.
+const shimsDirName = "shims"
+
+// shimMarker is included in every shim so stale shims can be found and removed.
+const shimMarker = "Code generated by gomodrun. DO NOT EDIT."
+
+// cmdEscaper escapes values written in to .cmd shims, where % expands variables even inside quotes.
+var cmdEscaper = strings.NewReplacer("%", "%%")
+
+// gomodrunModule is the package shims `go run` when gomodrun is not installed.
+const gomodrunModule = "github.com/dustinblackman/gomodrun/cmd/gomodrun"
+
+// ShimOptions contains parameters used when writing shims.
+type ShimOptions struct {
+	GomodrunPath    string // Command shims use to invoke gomodrun. Defaults to gomodrun.
+	GomodrunVersion string // Version of gomodrun shims `go run` when gomodrun is not on PATH. Defaults to gomodrun-version in .gomodrun.yml, then the version of gomodrun required in go.mod.
+}
+
+// getShimVersion returns the version of gomodrun shims `go run`, pinned in the project so shims are the same on every
+// machine. An empty string disables the fallback.
+func getShimVersion(pkgRoot string, options *ShimOptions) (string, error) {
+	if options.GomodrunVersion != "" {
+		return options.GomodrunVersion, nil
+	}
+
+	config, err := LoadConfig(pkgRoot)
+	if err != nil {
+		return "", err
+	}
+	if config.GomodrunVersion != "" {
+		return config.GomodrunVersion, nil
+	}
+
+	mod, err := getGoMod(pkgRoot)
+	if err != nil {
+		return "", err
+	}
+	if req := getRequire(mod, gomodrunModule); req != nil {
+		return req.Mod.Version, nil
+	}
+
+	return "", nil
+}
+
+// getShimScript returns a POSIX shell shim for binName. The tool name follows `--` so tools named like a gomodrun
+// command, such as env or lock, still run the tool.
+func getShimScript(pkgRoot, binName string, relative bool, options *ShimOptions) string {
+	root := fmt.Sprintf(`root="%s"`, posixEscaper.Replace(pkgRoot))
+	if relative {
+		root = fmt.Sprintf(`root="$(cd "$(dirname "$0")/%s" && pwd)"`, posixEscaper.Replace(filepath.ToSlash(pkgRoot)))
+	}
+
+	gomodrunPath := posixEscaper.Replace(options.GomodrunPath)
+	run := fmt.Sprintf(`exec "%s" -r "$root" -- %s "$@"`, gomodrunPath, binName)
+	if options.GomodrunVersion != "" {
+		run = fmt.Sprintf(`if command -v "%s" >/dev/null 2>&1; then
+	%s
+fi
+exec go run %s@%s -r "$root" -- %s "$@"`, gomodrunPath, run, gomodrunModule, options.GomodrunVersion, binName)
+	}
+
+	return fmt.Sprintf("#!/bin/sh\n# %s\n%s\n%s\n", shimMarker, root, run)
+}
+
+func getShimCmd(pkgRoot, binName string, relative bool, options *ShimOptions) string {
+	root := fmt.Sprintf(`set "root=%s"`, cmdEscaper.Replace(pkgRoot))
+	if relative {
+		root = fmt.Sprintf(`set "root=%%~dp0%s"`, cmdEscaper.Replace(strings.ReplaceAll(pkgRoot, "/", "\\")))
+	}
+
+	gomodrunPath := cmdEscaper.Replace(options.GomodrunPath)
+	run := fmt.Sprintf(`"%s" -r "%%root%%" -- %s %%*`, gomodrunPath, binName)
+	if options.GomodrunVersion != "" {
+		run = fmt.Sprintf("where \"%s\" >nul 2>nul\r\nif %%ERRORLEVEL%% equ 0 (\r\n  %s\r\n) else (\r\n  go run %s@%s -r \"%%root%%\" -- %s %%*\r\n)",
+			gomodrunPath, run, gomodrunModule, options.GomodrunVersion, binName)
+	}
+
+	return fmt.Sprintf("@echo off\r\nrem %s\r\n%s\r\n%s\r\nexit /b %%ERRORLEVEL%%\r\n", shimMarker, root, run)
+}
+
+// getShims returns the content of every shim that should exist in shimDir, keyed by file name.
+func getShims(pkgRoot, shimDir string, relative bool, options *ShimOptions) (map[string]string, error) {
+	if options == nil {
+		options = &ShimOptions{}
+	}
+
+	binNames, err := GetToolBinNames(pkgRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	shimOptions := *options
+	if shimOptions.GomodrunPath == "" {
+		shimOptions.GomodrunPath = "gomodrun"
+	}
+
+	shimOptions.GomodrunVersion, err = getShimVersion(pkgRoot, options)
+	if err != nil {
+		return nil, err
+	}
+
+	root := pkgRoot
+	if relative {
+		root, err = filepath.Rel(shimDir, pkgRoot)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	shims := map[string]string{}
+	for _, binName := range binNames {
+		if relative || runtime.GOOS != "windows" {
+			shims[binName] = getShimScript(root, binName, relative, &shimOptions)
+		}
+
+		if relative || runtime.GOOS == "windows" {
+			shims[binName+".cmd"] = getShimCmd(root, binName, relative, &shimOptions)
+		}
+	}
+
+	return shims, nil
+}
+
+// getStaleShims returns generated shims in shimDir that are not in shims.
+func getStaleShims(shimDir string, shims map[string]string) ([]string, error) {
+	stale := []string{}
+	files, err := ioutil.ReadDir(shimDir)
+	if os.IsNotExist(err) {
+		return stale, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for _, file := range files {
+		if _, ok := shims[file.Name()]; ok || file.IsDir() {
+			continue
+		}
+
+		content, err := ioutil.ReadFile(path.Join(shimDir, file.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		if bytes.Contains(content, []byte(shimMarker)) {
+			stale = append(stale, file.Name())
+		}
+	}
+
+	return stale, nil
+}
+
+// writeShims writes shims to shimDir and removes stale shims, returning the paths of the written shims.
+func writeShims(shimDir string, shims map[string]string) ([]string, error) {
+	err := os.MkdirAll(shimDir, os.ModePerm)
+	if err != nil {
+		return nil, err
+	}
+
+	stale, err := getStaleShims(shimDir, shims)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, shimName := range stale {
+		err = os.Remove(path.Join(shimDir, shimName))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	shimPaths := []string{}
+	for shimName, content := range shims {
+		shimPath := path.Join(shimDir, shimName)
+		err = ioutil.WriteFile(shimPath, []byte(content), 0o755) //nolint:gosec // Shims must be executable.
+		if err != nil {
+			return nil, err
+		}
+		shimPaths = append(shimPaths, shimPath)
+	}
+	sort.Strings(shimPaths)
+
+	return shimPaths, nil
+}
+
+func getShimPaths(pkgRoot, shimDir string) (string, string, error) {
+	var err error
+	if pkgRoot == "" {
+		pkgRoot, err = GetPkgRoot()
+		if err != nil {
+			return "", "", err
+		}
+	}
+
+	pkgRoot, err = filepath.Abs(pkgRoot)
+	if err != nil {
+		return "", "", err
+	}
+
+	if shimDir == "" {
+		return pkgRoot, path.Join(pkgRoot, ".gomodrun", shimsDirName), nil
+	}
+
+	shimDir, err = filepath.Abs(shimDir)
+	if err != nil {
+		return "", "", err
+	}
+
+	return pkgRoot, shimDir, nil
+}
+
+// CreateShimDir writes a shim for every tool in the tools file to .gomodrun/shims, removing shims for tools that no
+// longer exist, and returns the directory path. Shims invoke gomodrun so tools are rebuilt when go.mod changes.
+func CreateShimDir(pkgRoot string, options *ShimOptions) (string, error) {
+	pkgRoot, shimDir, err := getShimPaths(pkgRoot, "")
+	if err != nil {
+		return "", err
+	}
+
+	shims, err := getShims(pkgRoot, shimDir, false, options)
+	if err != nil {
+		return "", err
+	}
+
+	_, err = writeShims(shimDir, shims)
+	if err != nil {
+		return "", err
+	}
+
+	return shimDir, nil
+}
+
+// WriteShims writes a POSIX shell and Windows .cmd shim for every tool in the tools file to shimDir, removing stale
+// shims, and returns the paths of the written shims. Shims locate the package root relative to themselves so they can
+// be committed to the repository.
+func WriteShims(pkgRoot, shimDir string, options *ShimOptions) ([]string, error) {
+	pkgRoot, shimDir, err := getShimPaths(pkgRoot, shimDir)
+	if err != nil {
+		return nil, err
+	}
+
+	shims, err := getShims(pkgRoot, shimDir, true, options)
+	if err != nil {
+		return nil, err
+	}
+
+	return writeShims(shimDir, shims)
+}
+
+// CheckShims returns the names of shims in shimDir that are missing, out of date, or for tools that are no longer in
+// the tools file. An empty list means the shims are in sync.
+func CheckShims(pkgRoot, shimDir string, options *ShimOptions) ([]string, error) {
+	pkgRoot, shimDir, err := getShimPaths(pkgRoot, shimDir)
+	if err != nil {
+		return nil, err
+	}
+
+	shims, err := getShims(pkgRoot, shimDir, true, options)
+	if err != nil {
+		return nil, err
+	}
+
+	outOfSync, err := getStaleShims(shimDir, shims)
+	if err != nil {
+		return nil, err
+	}
+
+	for shimName, content := range shims {
+		existing, err := ioutil.ReadFile(path.Join(shimDir, shimName))
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+
+		if string(existing) != content {
+			outOfSync = append(outOfSync, shimName)
+		}
+	}
+	sort.Strings(outOfSync)
+
+	return outOfSync, nil
+}
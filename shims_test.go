@@ -0,0 +1,257 @@
+package gomodrun
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path"
+	"runtime"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/otiai10/copy"
+)
+
+var _ = Describe("shims", func() {
+	var tempDir string
+
+	BeforeEach(func() {
+		var err error
+		tempDir, err = ioutil.TempDir(os.TempDir(), "gomodrun-shims")
+		if err != nil {
+			panic(err)
+		}
+
+		//nolint:dogsled // Test file, don't need any of the extra values
+		_, filename, _, _ := runtime.Caller(0)
+		err = copy.Copy(path.Join(path.Dir(filename), "./tests/alternative-tools-dir"), tempDir)
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	AfterEach(func() {
+		err := os.RemoveAll(tempDir)
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	Context("CreateShimDir", func() {
+		It("should write a shim for every tool that invokes gomodrun", func() {
+			if runtime.GOOS == "windows" {
+				Skip("shell shims are not written on Windows")
+			}
+
+			shimDir, err := CreateShimDir(tempDir, &ShimOptions{GomodrunPath: "/usr/local/bin/gomodrun"})
+			Expect(err).To(BeNil())
+			Expect(shimDir).To(Equal(path.Join(tempDir, ".gomodrun", shimsDirName)))
+
+			content, err := ioutil.ReadFile(path.Join(shimDir, "hello-world"))
+			Expect(err).To(BeNil())
+			Expect(string(content)).To(ContainSubstring(`root="` + tempDir + `"`))
+			Expect(string(content)).To(ContainSubstring(`exec "/usr/local/bin/gomodrun" -r "$root" -- hello-world "$@"`))
+
+			info, err := os.Stat(path.Join(shimDir, "hello-world"))
+			Expect(err).To(BeNil())
+			Expect(info.Mode() & 0o111).ToNot(BeZero())
+		})
+
+		It("should remove shims for tools no longer in the tools file", func() {
+			staleShim := path.Join(tempDir, ".gomodrun", shimsDirName, "not-real")
+			err := os.MkdirAll(path.Dir(staleShim), 0750)
+			Expect(err).To(BeNil())
+			err = ioutil.WriteFile(staleShim, []byte("# "+shimMarker), 0600)
+			Expect(err).To(BeNil())
+
+			_, err = CreateShimDir(tempDir, &ShimOptions{})
+			Expect(err).To(BeNil())
+
+			_, err = os.Stat(staleShim)
+			Expect(os.IsNotExist(err)).To(BeTrue())
+		})
+
+		It("should treat nil options as the defaults", func() {
+			shimDir, err := CreateShimDir(tempDir, nil)
+			Expect(err).To(BeNil())
+
+			_, err = WriteShims(tempDir, path.Join(tempDir, "bin"), nil)
+			Expect(err).To(BeNil())
+
+			outOfSync, err := CheckShims(tempDir, path.Join(tempDir, "bin"), nil)
+			Expect(err).To(BeNil())
+			Expect(outOfSync).To(BeEmpty())
+
+			_, err = Env(tempDir, "bash", nil)
+			Expect(err).To(BeNil())
+			Expect(shimDir).To(Equal(path.Join(tempDir, ".gomodrun", shimsDirName)))
+		})
+	})
+
+	Context("WriteShims", func() {
+		It("should write POSIX and Windows shims that locate the package root relative to themselves", func() {
+			shimDir := path.Join(tempDir, "bin")
+			shimPaths, err := WriteShims(tempDir, shimDir, &ShimOptions{GomodrunVersion: "v0.4.5"})
+			Expect(err).To(BeNil())
+			Expect(shimPaths).To(Equal([]string{path.Join(shimDir, "hello-world"), path.Join(shimDir, "hello-world.cmd")}))
+
+			content, err := ioutil.ReadFile(path.Join(shimDir, "hello-world"))
+			Expect(err).To(BeNil())
+			Expect(string(content)).To(ContainSubstring(`root="$(cd "$(dirname "$0")/.." && pwd)"`))
+			Expect(string(content)).To(ContainSubstring("exec go run github.com/dustinblackman/gomodrun/cmd/gomodrun@v0.4.5"))
+
+			content, err = ioutil.ReadFile(path.Join(shimDir, "hello-world.cmd"))
+			Expect(err).To(BeNil())
+			Expect(string(content)).To(ContainSubstring(`set "root=%~dp0.."`))
+		})
+
+		It("should invoke gomodrun with the package root and arguments", func() {
+			if runtime.GOOS == "windows" {
+				Skip("POSIX shims can not be executed on Windows")
+			}
+
+			fakeBinDir := path.Join(tempDir, "fake")
+			err := os.MkdirAll(fakeBinDir, 0750)
+			Expect(err).To(BeNil())
+			err = ioutil.WriteFile(path.Join(fakeBinDir, "gomodrun"), []byte("#!/bin/sh\necho \"$@\"\n"), 0o755) //nolint:gosec // Must be executable.
+			Expect(err).To(BeNil())
+
+			shimDir := path.Join(tempDir, "bin")
+			_, err = WriteShims(tempDir, shimDir, &ShimOptions{GomodrunVersion: "v0.4.5"})
+			Expect(err).To(BeNil())
+
+			cmd := exec.Command(path.Join(shimDir, "hello-world"), "run", "./...")
+			cmd.Env = []string{"PATH=" + fakeBinDir + ":/usr/bin:/bin"}
+			output, err := cmd.CombinedOutput()
+			Expect(err).To(BeNil())
+			Expect(string(output)).To(Equal("-r " + tempDir + " -- hello-world run ./...\n"))
+		})
+	})
+
+	Context("getShimScript", func() {
+		It("should run tools named like a gomodrun command instead of the command", func() {
+			if runtime.GOOS == "windows" {
+				Skip("POSIX shims can not be executed on Windows")
+			}
+
+			fakeBinDir := path.Join(tempDir, "fake")
+			err := os.MkdirAll(fakeBinDir, 0750)
+			Expect(err).To(BeNil())
+			err = ioutil.WriteFile(path.Join(fakeBinDir, "gomodrun"), []byte("#!/bin/sh\necho \"$@\"\n"), 0o755) //nolint:gosec // Must be executable.
+			Expect(err).To(BeNil())
+
+			for _, options := range []*ShimOptions{{GomodrunPath: "gomodrun"}, {GomodrunPath: "not-installed", GomodrunVersion: "v0.4.5"}} {
+				script := getShimScript(tempDir, "env", false, options)
+				cmd := exec.Command("sh", "-c", script, "env", "bash")
+				cmd.Env = []string{"PATH=" + fakeBinDir + ":/usr/bin:/bin"}
+				if options.GomodrunVersion != "" {
+					err = ioutil.WriteFile(path.Join(fakeBinDir, "go"), []byte("#!/bin/sh\necho \"$@\"\n"), 0o755) //nolint:gosec // Must be executable.
+					Expect(err).To(BeNil())
+				}
+				output, err := cmd.CombinedOutput()
+				Expect(err).To(BeNil())
+				Expect(string(output)).To(HaveSuffix("-r " + tempDir + " -- env bash\n"))
+			}
+		})
+
+		It("should escape the package root and gomodrun path", func() {
+			if runtime.GOOS == "windows" {
+				Skip("POSIX shims can not be executed on Windows")
+			}
+
+			fakeBinDir := path.Join(tempDir, "fake $dir`x`")
+			err := os.MkdirAll(fakeBinDir, 0750)
+			Expect(err).To(BeNil())
+			gomodrunPath := path.Join(fakeBinDir, "gomodrun")
+			err = ioutil.WriteFile(gomodrunPath, []byte("#!/bin/sh\necho \"$@\"\n"), 0o755) //nolint:gosec // Must be executable.
+			Expect(err).To(BeNil())
+
+			pkgRoot := "/projects/a\"b$c`d`"
+			script := getShimScript(pkgRoot, "hello-world", false, &ShimOptions{GomodrunPath: gomodrunPath})
+			output, err := exec.Command("sh", "-c", script, "hello-world", "run").CombinedOutput()
+			Expect(err).To(BeNil())
+			Expect(string(output)).To(Equal("-r " + pkgRoot + " -- hello-world run\n"))
+		})
+	})
+
+	Context("getShimCmd", func() {
+		It("should pass the tool after -- in both the gomodrun and go run commands", func() {
+			cmd := getShimCmd("..", "env", true, &ShimOptions{GomodrunPath: "gomodrun", GomodrunVersion: "v0.4.5"})
+			Expect(cmd).To(ContainSubstring(`"gomodrun" -r "%root%" -- env %*`))
+			Expect(cmd).To(ContainSubstring(`go run github.com/dustinblackman/gomodrun/cmd/gomodrun@v0.4.5 -r "%root%" -- env %*`))
+		})
+	})
+
+	Context("CheckShims", func() {
+		var shimDir string
+
+		BeforeEach(func() {
+			shimDir = path.Join(tempDir, "bin")
+			_, err := WriteShims(tempDir, shimDir, &ShimOptions{})
+			if err != nil {
+				panic(err)
+			}
+		})
+
+		It("should return nothing when shims are in sync", func() {
+			outOfSync, err := CheckShims(tempDir, shimDir, &ShimOptions{})
+			Expect(err).To(BeNil())
+			Expect(outOfSync).To(BeEmpty())
+		})
+
+		It("should return missing, modified and stale shims", func() {
+			err := os.Remove(path.Join(shimDir, "hello-world.cmd"))
+			Expect(err).To(BeNil())
+			err = ioutil.WriteFile(path.Join(shimDir, "hello-world"), []byte("#!/bin/sh\n"), 0o755) //nolint:gosec // Must be executable.
+			Expect(err).To(BeNil())
+			err = ioutil.WriteFile(path.Join(shimDir, "not-real"), []byte("# "+shimMarker), 0o755) //nolint:gosec // Must be executable.
+			Expect(err).To(BeNil())
+			err = ioutil.WriteFile(path.Join(shimDir, "README.md"), []byte("not a shim"), 0600)
+			Expect(err).To(BeNil())
+
+			outOfSync, err := CheckShims(tempDir, shimDir, &ShimOptions{})
+			Expect(err).To(BeNil())
+			Expect(outOfSync).To(Equal([]string{"hello-world", "hello-world.cmd", "not-real"}))
+		})
+
+		It("should return shims that are out of date with the gomodrun version", func() {
+			outOfSync, err := CheckShims(tempDir, shimDir, &ShimOptions{GomodrunVersion: "v0.4.5"})
+			Expect(err).To(BeNil())
+			Expect(outOfSync).To(Equal([]string{"hello-world", "hello-world.cmd"}))
+		})
+
+		It("should use the gomodrun version pinned in .gomodrun.yml", func() {
+			err := ioutil.WriteFile(path.Join(tempDir, ConfigFileName), []byte("gomodrun-version: v0.4.5\n"), 0600)
+			Expect(err).To(BeNil())
+
+			outOfSync, err := CheckShims(tempDir, shimDir, &ShimOptions{})
+			Expect(err).To(BeNil())
+			Expect(outOfSync).To(Equal([]string{"hello-world", "hello-world.cmd"}))
+
+			_, err = WriteShims(tempDir, shimDir, &ShimOptions{})
+			Expect(err).To(BeNil())
+			content, err := ioutil.ReadFile(path.Join(shimDir, "hello-world"))
+			Expect(err).To(BeNil())
+			Expect(string(content)).To(ContainSubstring("exec go run github.com/dustinblackman/gomodrun/cmd/gomodrun@v0.4.5"))
+
+			outOfSync, err = CheckShims(tempDir, shimDir, &ShimOptions{})
+			Expect(err).To(BeNil())
+			Expect(outOfSync).To(BeEmpty())
+		})
+
+		It("should use the gomodrun version required in go.mod", func() {
+			goMod := path.Join(tempDir, "go.mod")
+			data, err := ioutil.ReadFile(goMod)
+			Expect(err).To(BeNil())
+			err = ioutil.WriteFile(goMod, append(data, []byte("\nrequire github.com/dustinblackman/gomodrun v0.4.6\n")...), 0600)
+			Expect(err).To(BeNil())
+
+			_, err = WriteShims(tempDir, shimDir, &ShimOptions{})
+			Expect(err).To(BeNil())
+			content, err := ioutil.ReadFile(path.Join(shimDir, "hello-world"))
+			Expect(err).To(BeNil())
+			Expect(string(content)).To(ContainSubstring("exec go run github.com/dustinblackman/gomodrun/cmd/gomodrun@v0.4.6"))
+		})
+	})
+})
@@ -5,13 +5,62 @@
 package gomodrun
 
 import (
+	"fmt"
 	"io/ioutil"
 	"os"
 	"path"
 	"path/filepath"
+	"sort"
 	"strings"
+
+	"golang.org/x/mod/semver"
 )
 
+// TidyOptions contains parameters that change how Tidy cleans .gomodrun.
+type TidyOptions struct {
+	DryRun         bool     // Report what would be removed without deleting anything.
+	KeepGoVersions []string // Go versions to keep in addition to the current go version, such as go1.21.5.
+	KeepRecent     int      // Keep binaries for this many of the newest go versions found in .gomodrun.
+}
+
+// GoVersionUsage is the disk usage of a single go version directory in .gomodrun.
+type GoVersionUsage struct {
+	Version string // Go version the binaries were built with.
+	Size    int64  // Size in bytes before any binaries were removed.
+	Kept    bool   // True when the go version directory was kept.
+}
+
+// TidyEntry is a go version directory or binary that was inspected by Tidy.
+type TidyEntry struct {
+	Path   string // Absolute path to the directory or binary.
+	Size   int64  // Size in bytes, including all children of a directory.
+	Reason string // Why the entry was kept or removed.
+}
+
+// TidyReport contains every entry Tidy kept or removed.
+type TidyReport struct {
+	PkgRoot    string           // Root directory of go.mod with tools.
+	DryRun     bool             // True when nothing was deleted.
+	Kept       []TidyEntry      // Entries left in .gomodrun.
+	Removed    []TidyEntry      // Entries deleted, or that would be deleted on a dry run.
+	GoVersions []GoVersionUsage // Disk usage of each go version directory.
+}
+
+// ReclaimedBytes returns the total size of all removed entries.
+func (r *TidyReport) ReclaimedBytes() int64 {
+	var total int64
+	for _, entry := range r.Removed {
+		total += entry.Size
+	}
+
+	return total
+}
+
+// isGoVersionDir returns true for directories in .gomodrun that contain binaries built with a single go version.
+func isGoVersionDir(file os.FileInfo) bool {
+	return file.IsDir() && strings.HasPrefix(file.Name(), "go")
+}
+
 func cleanEmptyDirectory(root string) error {
 	dirs := []string{}
 	err := filepath.Walk(root, func(filePath string, info os.FileInfo, err error) error {
@@ -49,74 +98,215 @@ func cleanEmptyDirectory(root string) error {
 	return err
 }
 
+// getAllBins returns every cached binary in root. Build logs and completion markers are left out as they're handled
+// with their binary, unless the build failed and left only a log behind.
 func getAllBins(root string) ([]string, error) {
-	binPaths := []string{}
+	filePaths := []string{}
 	err := filepath.Walk(root, func(filePath string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
 
 		if !info.IsDir() {
+			filePaths = append(filePaths, filePath)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sidecars := map[string]bool{}
+	for _, filePath := range filePaths {
+		if !isSidecar(filePath) {
+			for _, sidecarPath := range getSidecarPaths(filePath) {
+				sidecars[sidecarPath] = true
+			}
+		}
+	}
+
+	binPaths := []string{}
+	for _, filePath := range filePaths {
+		if !sidecars[filePath] {
 			binPaths = append(binPaths, filePath)
 		}
+	}
+
+	return binPaths, nil
+}
+
+func getDiskUsage(root string) (int64, error) {
+	var size int64
+	err := filepath.Walk(root, func(filePath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !info.IsDir() {
+			size += info.Size()
+		}
 
 		return nil
 	})
 
-	return binPaths, err
+	return size, err
+}
+
+// goVersionToSemver converts a go version such as go1.21rc1 in to a comparable semver string such as v1.21.0-rc1.
+func goVersionToSemver(goVersion string) string {
+	version := strings.TrimPrefix(goVersion, "go")
+	prerelease := ""
+	for _, tag := range []string{"rc", "beta", "alpha"} {
+		if idx := strings.Index(version, tag); idx != -1 {
+			prerelease = "-" + version[idx:]
+			version = version[:idx]
+			break
+		}
+	}
+
+	for strings.Count(version, ".") < 2 {
+		version += ".0"
+	}
+
+	return "v" + version + prerelease
+}
+
+// getKeptGoVersions returns the go versions directories that should be kept, mapped to the reason they're kept.
+func getKeptGoVersions(goVersion string, goVersions []string, options *TidyOptions) map[string]string {
+	kept := map[string]string{
+		goVersion: "current go version",
+	}
+
+	for _, version := range options.KeepGoVersions {
+		if _, ok := kept[version]; !ok {
+			kept[version] = "listed in go versions to keep"
+		}
+	}
+
+	sorted := append([]string{}, goVersions...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return semver.Compare(goVersionToSemver(sorted[i]), goVersionToSemver(sorted[j])) > 0
+	})
+
+	for idx := 0; idx < options.KeepRecent && idx < len(sorted); idx++ {
+		if _, ok := kept[sorted[idx]]; !ok {
+			kept[sorted[idx]] = fmt.Sprintf("one of the %d newest go versions", options.KeepRecent)
+		}
+	}
+
+	return kept
 }
 
 // Tidy cleans .gomodrun of any outdated binaries.
 func Tidy(pkgRoot string) error {
+	_, err := TidyWithReport(pkgRoot, &TidyOptions{})
+	return err
+}
+
+// TidyWithReport cleans .gomodrun of any outdated binaries, returning a report of every entry that was kept or removed.
+func TidyWithReport(pkgRoot string, options *TidyOptions) (*TidyReport, error) {
+	runner, err := NewRunner(&Options{PkgRoot: pkgRoot})
+	if err != nil {
+		return nil, err
+	}
+
+	return runner.Tidy(options)
+}
+
+// Tidy cleans the cache of any outdated binaries, returning a report of every entry that was kept or removed.
+func (r *Runner) Tidy(options *TidyOptions) (*TidyReport, error) {
+	if options == nil {
+		options = &TidyOptions{}
+	}
+
 	var err error
-	if pkgRoot == "" {
-		pkgRoot, err = GetPkgRoot()
-		if err != nil {
-			return err
-		}
+	pkgRoot := r.PkgRoot
+	report := &TidyReport{
+		PkgRoot:    pkgRoot,
+		DryRun:     options.DryRun,
+		Kept:       []TidyEntry{},
+		Removed:    []TidyEntry{},
+		GoVersions: []GoVersionUsage{},
 	}
 
-	gmrRoot := path.Join(pkgRoot, ".gomodrun")
+	gmrRoot := r.cacheDir()
 	if _, err = os.Stat(gmrRoot); os.IsNotExist(err) {
-		return nil
+		return report, nil
 	}
 
-	goVersion, err := getGoVersion()
+	goVersion, err := r.toolchain().Version()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	gmrRootFiles, err := ioutil.ReadDir(gmrRoot)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
+	goVersions := []string{}
 	for _, file := range gmrRootFiles {
-		if file.Name() != goVersion {
-			err = os.RemoveAll(path.Join(gmrRoot, file.Name()))
-			if err != nil {
-				return err
-			}
+		if isGoVersionDir(file) {
+			goVersions = append(goVersions, file.Name())
 		}
 	}
+	keptGoVersions := getKeptGoVersions(goVersion, goVersions, options)
 
-	binPaths, err := getAllBins(gmrRoot)
-	if err != nil {
-		return err
+	binPaths := []string{}
+	for _, file := range gmrRootFiles {
+		if !isGoVersionDir(file) {
+			continue
+		}
+
+		filePath := path.Join(gmrRoot, file.Name())
+		size, sizeErr := getDiskUsage(filePath)
+		if sizeErr != nil {
+			return nil, sizeErr
+		}
+
+		_, kept := keptGoVersions[file.Name()]
+		report.GoVersions = append(report.GoVersions, GoVersionUsage{
+			Version: file.Name(),
+			Size:    size,
+			Kept:    kept,
+		})
+
+		if kept {
+			versionBins, binsErr := getAllBins(filePath)
+			if binsErr != nil {
+				return nil, binsErr
+			}
+			binPaths = append(binPaths, versionBins...)
+			continue
+		}
+
+		report.Removed = append(report.Removed, TidyEntry{
+			Path:   filePath,
+			Size:   size,
+			Reason: fmt.Sprintf("built with %s, which is not the current go version %s or a go version to keep", file.Name(), goVersion),
+		})
+
+		if !options.DryRun {
+			err = os.RemoveAll(filePath)
+			if err != nil {
+				return nil, err
+			}
+		}
 	}
 
 	if len(binPaths) == 0 {
-		return nil
+		return report, nil
 	}
 
 	pkg, err := getToolsPkg(pkgRoot)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	mod, err := getGoMod(pkgRoot)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	versionedImports := []string{}
@@ -130,26 +320,53 @@ func Tidy(pkgRoot string) error {
 	}
 
 	for _, binPath := range binPaths {
-		validBin := false
+		size, sizeErr := getCacheEntrySize(binPath)
+		if sizeErr != nil {
+			return nil, sizeErr
+		}
+
+		entry := TidyEntry{
+			Path: binPath,
+			Size: size,
+		}
+
+		if isEphemeralBin(gmrRoot, binPath) {
+			entry.Reason = "built for a module/pkg@version run, gc evicts it"
+			report.Kept = append(report.Kept, entry)
+			continue
+		}
+
 		for _, versionedImport := range versionedImports {
 			if strings.Contains(binPath, versionedImport) {
-				validBin = true
+				entry.Reason = fmt.Sprintf("%s is required in go.mod", versionedImport)
 				break
 			}
 		}
 
-		if !validBin {
-			err = os.Remove(binPath)
+		if entry.Reason != "" {
+			report.Kept = append(report.Kept, entry)
+			continue
+		}
+
+		entry.Reason = "module version is no longer required by the tools file and go.mod"
+		report.Removed = append(report.Removed, entry)
+
+		if !options.DryRun {
+			err = removeCacheEntry(binPath)
 			if err != nil {
-				return err
+				return nil, err
 			}
 		}
 	}
 
-	err = cleanEmptyDirectory(pkgRoot)
+	if options.DryRun {
+		return report, nil
+	}
+
+	err = cleanEmptyDirectory(gmrRoot)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	return nil
+	return report, nil
 }
@@ -12,6 +12,47 @@ import (
 	"github.com/otiai10/copy"
 )
 
+// createTidyFixture returns a project fixture with binaries cached for the tidy specs to keep and drop.
+func createTidyFixture(goVersion string) string {
+	tempDir := createProjectFixture()
+	addTidyBins(tempDir, goVersion)
+
+	return tempDir
+}
+
+// addTidyBins caches hello-world v0.0.2 with goVersion, which tidy keeps, along with binaries and empty folders that
+// tidy drops.
+func addTidyBins(tempDir, goVersion string) {
+	bins := []string{
+		// Bins to keep
+		path.Join(goVersion, "github.com/dustinblackman/go-hello-world-test@v0.0.2/hello-world"),
+		// Bins to drop
+		path.Join(goVersion, "github.com/dustinblackman/go-hello-world-test@v0.0.1/hello-world"),
+		path.Join("go1.0.1", "github.com/dustinblackman/go-hello-world-test@v0.0.2/hello-world"),
+	}
+
+	emptyFolders := []string{
+		"github.com/dustinblackman/go-hello-world-test-no-gomod@v0.0.1",
+	}
+
+	for _, binPath := range bins {
+		writeFakeBin(path.Join(tempDir, ".gomodrun", binPath, path.Base(binPath)))
+	}
+
+	err := os.MkdirAll(path.Join(tempDir, "testdata", "empty"), 0750)
+	if err != nil {
+		panic(err)
+	}
+
+	for _, folderPath := range emptyFolders {
+		fullPath := path.Join(tempDir, ".gomodrun", goVersion, folderPath)
+		err = os.MkdirAll(path.Dir(fullPath), 0750)
+		if err != nil {
+			panic(err)
+		}
+	}
+}
+
 var _ = Describe("tidy", func() {
 	var tempDir string
 	var goVersion string
@@ -102,4 +143,116 @@ var _ = Describe("tidy", func() {
 		Expect(os.IsNotExist(existsErr)).To(BeTrue())
 
 	})
+
+	Context("TidyWithReport", func() {
+		var reportDir string
+
+		BeforeEach(func() {
+			reportDir = createTidyFixture(goVersion)
+		})
+
+		AfterEach(func() {
+			err := os.RemoveAll(reportDir)
+			if err != nil {
+				panic(err)
+			}
+		})
+
+		It("should remove binaries of other go versions", func() {
+			err := Tidy(reportDir)
+			Expect(err).To(BeNil())
+
+			_, existsErr := os.Stat(path.Join(reportDir, ".gomodrun", "go1.0.1"))
+			Expect(os.IsNotExist(existsErr)).To(BeTrue())
+		})
+
+		It("should not remove empty folders outside of .gomodrun", func() {
+			err := Tidy(reportDir)
+			Expect(err).To(BeNil())
+
+			_, existsErr := os.Stat(path.Join(reportDir, "testdata", "empty"))
+			Expect(os.IsNotExist(existsErr)).To(BeFalse())
+		})
+
+		It("should treat nil options as the defaults", func() {
+			report, err := TidyWithReport(reportDir, nil)
+			Expect(err).To(BeNil())
+			Expect(report.DryRun).To(BeFalse())
+			Expect(report.Removed).To(HaveLen(2))
+		})
+
+		It("should report kept and removed entries without deleting on a dry run", func() {
+			baseDir := path.Join(reportDir, ".gomodrun", goVersion)
+
+			report, err := TidyWithReport(reportDir, &TidyOptions{DryRun: true})
+			Expect(err).To(BeNil())
+			Expect(report.DryRun).To(BeTrue())
+
+			Expect(report.Kept).To(HaveLen(1))
+			Expect(report.Kept[0].Path).To(Equal(path.Join(baseDir, "github.com/dustinblackman/go-hello-world-test@v0.0.2/hello-world/hello-world")))
+			Expect(report.Kept[0].Reason).To(ContainSubstring("github.com/dustinblackman/go-hello-world-test@v0.0.2"))
+
+			Expect(report.Removed).To(HaveLen(2))
+			Expect(report.Removed[0].Path).To(Equal(path.Join(reportDir, ".gomodrun", "go1.0.1")))
+			Expect(report.Removed[0].Reason).To(ContainSubstring("go1.0.1"))
+			Expect(report.Removed[1].Path).To(Equal(path.Join(baseDir, "github.com/dustinblackman/go-hello-world-test@v0.0.1/hello-world/hello-world")))
+			Expect(report.ReclaimedBytes()).To(Equal(int64(6)))
+
+			for _, entry := range append(report.Kept, report.Removed...) {
+				_, existsErr := os.Stat(entry.Path)
+				Expect(os.IsNotExist(existsErr)).To(BeFalse())
+			}
+		})
+
+		It("should delete the removed entries when not a dry run", func() {
+			report, err := TidyWithReport(reportDir, &TidyOptions{})
+			Expect(err).To(BeNil())
+			Expect(report.DryRun).To(BeFalse())
+			Expect(report.Removed).To(HaveLen(2))
+
+			for _, entry := range report.Removed {
+				_, existsErr := os.Stat(entry.Path)
+				Expect(os.IsNotExist(existsErr)).To(BeTrue())
+			}
+		})
+
+		It("should keep binaries for listed go versions", func() {
+			report, err := TidyWithReport(reportDir, &TidyOptions{KeepGoVersions: []string{"go1.0.1"}})
+			Expect(err).To(BeNil())
+			Expect(report.Removed).To(HaveLen(1))
+			Expect(report.Kept).To(HaveLen(2))
+			Expect(report.GoVersions).To(ConsistOf(
+				GoVersionUsage{Version: "go1.0.1", Size: 3, Kept: true},
+				GoVersionUsage{Version: goVersion, Size: 6, Kept: true},
+			))
+
+			_, existsErr := os.Stat(path.Join(reportDir, ".gomodrun", "go1.0.1", "github.com/dustinblackman/go-hello-world-test@v0.0.2/hello-world/hello-world"))
+			Expect(os.IsNotExist(existsErr)).To(BeFalse())
+		})
+
+		It("should keep binaries for the newest go versions", func() {
+			oldest := path.Join(reportDir, ".gomodrun", "go1.0.0", "github.com/dustinblackman/go-hello-world-test@v0.0.2/hello-world/hello-world")
+			err := os.MkdirAll(path.Dir(oldest), 0750)
+			Expect(err).To(BeNil())
+			err = ioutil.WriteFile(oldest, []byte("bin"), 0600)
+			Expect(err).To(BeNil())
+
+			report, err := TidyWithReport(reportDir, &TidyOptions{KeepRecent: 2})
+			Expect(err).To(BeNil())
+			Expect(report.GoVersions).To(ConsistOf(
+				GoVersionUsage{Version: "go1.0.0", Size: 3, Kept: false},
+				GoVersionUsage{Version: "go1.0.1", Size: 3, Kept: true},
+				GoVersionUsage{Version: goVersion, Size: 6, Kept: true},
+			))
+
+			_, existsErr := os.Stat(path.Join(reportDir, ".gomodrun", "go1.0.0"))
+			Expect(os.IsNotExist(existsErr)).To(BeTrue())
+		})
+
+		It("should convert go versions to semver", func() {
+			Expect(goVersionToSemver("go1.22.3")).To(Equal("v1.22.3"))
+			Expect(goVersionToSemver("go1.21")).To(Equal("v1.21.0"))
+			Expect(goVersionToSemver("go1.21rc1")).To(Equal("v1.21.0-rc1"))
+		})
+	})
 })
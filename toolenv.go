@@ -0,0 +1,74 @@
+// Package gomodrun is the forgotten go tool that executes and caches binaries included in go.mod files.
+// This makes it easy to version cli tools in your projects such as `golangci-lint`
+// and `ginkgo` that are versioned locked to what you specify in `go.mod`.
+// Binaries are cached by go version and package version.
+package gomodrun
+
+import (
+	"os"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+// getEnvKey returns the name of a KEY=value environment variable, folding case on Windows where names aren't case
+// sensitive.
+func getEnvKey(entry string) string {
+	key := strings.SplitN(entry, "=", 2)[0]
+	if runtime.GOOS == "windows" {
+		return strings.ToUpper(key)
+	}
+
+	return key
+}
+
+// mergeEnv returns a copy of env with overrides set, replacing existing variables in place.
+func mergeEnv(env, overrides []string) []string {
+	result := append([]string{}, env...)
+	indexes := map[string]int{}
+	for idx, entry := range result {
+		indexes[getEnvKey(entry)] = idx
+	}
+
+	for _, entry := range overrides {
+		key := getEnvKey(entry)
+		if idx, ok := indexes[key]; ok {
+			result[idx] = entry
+			continue
+		}
+
+		indexes[key] = len(result)
+		result = append(result, entry)
+	}
+
+	return result
+}
+
+// getToolEnv returns the environment for binName, starting from Env or the current environment, adding the tool's
+// config env when not already set, and then ExtraEnv.
+func (r *Runner) getToolEnv(binName string) ([]string, error) {
+	env := r.Env
+	if env == nil {
+		env = os.Environ()
+	}
+
+	config, err := r.loadConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	existing := map[string]bool{}
+	for _, entry := range env {
+		existing[getEnvKey(entry)] = true
+	}
+
+	defaults := []string{}
+	for key, value := range config.Tools[strings.TrimSuffix(binName, ".exe")].Env {
+		if !existing[getEnvKey(key)] {
+			defaults = append(defaults, key+"="+value)
+		}
+	}
+	sort.Strings(defaults)
+
+	return mergeEnv(append(append([]string{}, env...), defaults...), r.ExtraEnv), nil
+}
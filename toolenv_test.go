@@ -0,0 +1,85 @@
+package gomodrun
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("toolenv", func() {
+	var tempDir string
+	var runner *Runner
+
+	BeforeEach(func() {
+		tempDir = createProjectFixture()
+		srcDir := path.Join(tempDir, "src")
+		err := os.MkdirAll(srcDir, 0750)
+		Expect(err).To(BeNil())
+		err = ioutil.WriteFile(path.Join(srcDir, "go.mod"), []byte("module example.com/hello-world\n"), 0600)
+		Expect(err).To(BeNil())
+
+		runner, err = NewRunner(&Options{PkgRoot: tempDir})
+		Expect(err).To(BeNil())
+		runner.Toolchain = &fakeToolchain{script: `pwd; echo "$GMR_A $GMR_B $GMR_C"`}
+		runner.Sources = &fakeSources{dir: srcDir}
+		runner.Cache = &fakeCache{dir: path.Join(tempDir, "cache")}
+	})
+
+	AfterEach(func() {
+		err := os.RemoveAll(tempDir)
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	It("should merge overrides in place", func() {
+		Expect(mergeEnv([]string{"A=1", "B=2"}, []string{"B=3", "C=4"})).To(Equal([]string{"A=1", "B=3", "C=4"}))
+	})
+
+	It("should set config env defaults without overriding the environment", func() {
+		config := "tools:\n  hello-world:\n    env:\n      GMR_A: config\n      GMR_B: config\n"
+		err := ioutil.WriteFile(path.Join(tempDir, ConfigFileName), []byte(config), 0600)
+		Expect(err).To(BeNil())
+
+		runner.Env = []string{"GMR_A=env"}
+		env, err := runner.getToolEnv("hello-world")
+		Expect(err).To(BeNil())
+		Expect(env).To(Equal([]string{"GMR_A=env", "GMR_B=config"}))
+
+		runner.ExtraEnv = []string{"GMR_A=extra", "GMR_C=extra"}
+		env, err = runner.getToolEnv("hello-world")
+		Expect(err).To(BeNil())
+		Expect(env).To(Equal([]string{"GMR_A=extra", "GMR_B=config", "GMR_C=extra"}))
+	})
+
+	It("should keep the current environment when only extra env is provided", func() {
+		runner.ExtraEnv = []string{"GMR_C=extra"}
+		env, err := runner.getToolEnv("hello-world")
+		Expect(err).To(BeNil())
+		Expect(env).To(ContainElement("GMR_C=extra"))
+		Expect(env).To(ContainElement("PATH=" + os.Getenv("PATH")))
+	})
+
+	It("should run the tool in Dir with the merged environment", func() {
+		workDir := path.Join(tempDir, "src")
+		err := ioutil.WriteFile(path.Join(tempDir, ConfigFileName), []byte("tools:\n  hello-world:\n    env:\n      GMR_B: config\n"), 0600)
+		Expect(err).To(BeNil())
+
+		stdout := &bytes.Buffer{}
+		runner.Stdout = stdout
+		runner.Dir = workDir
+		runner.ExtraEnv = []string{"GMR_A=extra"}
+		exitCode, err := runner.Run("hello-world", []string{})
+		Expect(err).To(BeNil())
+		Expect(exitCode).To(Equal(0))
+
+		realDir, err := filepath.EvalSymlinks(workDir)
+		Expect(err).To(BeNil())
+		Expect(stdout.String()).To(Equal(realDir + "\nextra config \n"))
+	})
+})
@@ -0,0 +1,242 @@
+// Package gomodrun is the forgotten go tool that executes and caches binaries included in go.mod files.
+// This makes it easy to version cli tools in your projects such as `golangci-lint`
+// and `ginkgo` that are versioned locked to what you specify in `go.mod`.
+// Binaries are cached by go version and package version.
+package gomodrun
+
+import (
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// getToolsFiles returns the paths to every file in root that is only built with the tools build tags, sorted by name.
+func getToolsFiles(root string) ([]string, error) {
+	toolsPkg, err := getToolsPkg(root)
+	if err != nil {
+		if _, ok := err.(*build.NoGoError); ok {
+			return []string{}, nil
+		}
+		return nil, err
+	}
+
+	defaultFiles := map[string]bool{}
+	defaultPkg, err := build.Default.ImportDir(root, 0)
+	if err == nil {
+		for _, file := range defaultPkg.GoFiles {
+			defaultFiles[file] = true
+		}
+	}
+
+	toolsFiles := []string{}
+	for _, file := range toolsPkg.GoFiles {
+		if !defaultFiles[file] {
+			toolsFiles = append(toolsFiles, path.Join(root, file))
+		}
+	}
+	sort.Strings(toolsFiles)
+
+	return toolsFiles, nil
+}
+
+// getToolsFile returns the path to the first file in root that is only built with the tools build tag, or an empty
+// string when there isn't one.
+func getToolsFile(root string) (string, error) {
+	toolsFiles, err := getToolsFiles(root)
+	if err != nil || len(toolsFiles) == 0 {
+		return "", err
+	}
+
+	return toolsFiles[0], nil
+}
+
+// hasToolImport returns true when src imports importPath.
+func hasToolImport(src []byte, importPath string) (bool, error) {
+	file, err := parser.ParseFile(token.NewFileSet(), "", src, parser.ImportsOnly)
+	if err != nil {
+		return false, err
+	}
+
+	for _, importSpec := range file.Imports {
+		if existing, _ := strconv.Unquote(importSpec.Path.Value); existing == importPath {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// getToolsPkgName returns the package name used by go files in root, falling back to a name derived from the
+// directory.
+func getToolsPkgName(root string) (string, error) {
+	pkg, err := getToolsPkg(root)
+	if err == nil && pkg.Name != "" {
+		return pkg.Name, nil
+	}
+
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return "", err
+	}
+
+	name := strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+		return -1
+	}, filepath.Base(absRoot))
+
+	if name == "" || unicode.IsDigit(rune(name[0])) || token.Lookup(name).IsKeyword() {
+		return "tools", nil
+	}
+
+	return name, nil
+}
+
+// newToolsFile returns the source of a tools file importing importPaths, only built when every tag in tags is set.
+func newToolsFile(pkgName string, tags, importPaths []string) ([]byte, error) {
+	src := fmt.Sprintf("//go:build %s\n// +build %s\n\npackage %s\n", strings.Join(tags, " && "), strings.Join(tags, ","), pkgName)
+	if len(importPaths) > 0 {
+		src += "\nimport (\n"
+		for _, importPath := range importPaths {
+			src += fmt.Sprintf("\t_ %s\n", strconv.Quote(importPath))
+		}
+		src += ")\n"
+	}
+
+	return format.Source([]byte(src))
+}
+
+// getImportDecls returns the import declarations in a parsed file.
+func getImportDecls(file *ast.File) []*ast.GenDecl {
+	decls := []*ast.GenDecl{}
+	for _, decl := range file.Decls {
+		if genDecl, ok := decl.(*ast.GenDecl); ok && genDecl.Tok == token.IMPORT {
+			decls = append(decls, genDecl)
+		}
+	}
+
+	return decls
+}
+
+// addToolImport adds a blank import of importPath to the tools file source, returning the formatted source. The
+// source is returned unchanged if importPath is already imported.
+func addToolImport(src []byte, importPath string) ([]byte, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", src, parser.ImportsOnly|parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, spec := range file.Imports {
+		if existing, _ := strconv.Unquote(spec.Path.Value); existing == importPath {
+			return src, nil
+		}
+	}
+
+	importLine := "_ " + strconv.Quote(importPath)
+	offset := fset.Position(file.Name.End()).Offset
+	insert := fmt.Sprintf("\n\nimport (\n\t%s\n)", importLine)
+
+	importDecls := getImportDecls(file)
+	if len(importDecls) > 0 {
+		lastDecl := importDecls[len(importDecls)-1]
+		if lastDecl.Rparen.IsValid() {
+			// Insert in sorted order before the first larger import and its comments, so formatting doesn't need to
+			// move comments between imports.
+			offset = fset.Position(lastDecl.Rparen).Offset
+			insert = fmt.Sprintf("\t%s\n", importLine)
+			for _, spec := range lastDecl.Specs {
+				importSpec := spec.(*ast.ImportSpec)
+				if existing, _ := strconv.Unquote(importSpec.Path.Value); existing < importPath {
+					continue
+				}
+
+				pos := importSpec.Pos()
+				if importSpec.Doc != nil {
+					pos = importSpec.Doc.Pos()
+				}
+				offset, _ = getLineRange(src, fset.Position(pos).Offset, fset.Position(pos).Offset)
+				break
+			}
+		} else {
+			offset = fset.Position(lastDecl.End()).Offset
+			insert = "\nimport " + importLine
+		}
+	}
+
+	result := append([]byte{}, src[:offset]...)
+	result = append(result, insert...)
+	result = append(result, src[offset:]...)
+
+	return format.Source(result)
+}
+
+// getLineRange expands the start and end offsets to cover the full lines they're on, including the trailing newline.
+func getLineRange(src []byte, start, end int) (int, int) {
+	for start > 0 && src[start-1] != '\n' {
+		start--
+	}
+
+	for end < len(src) && src[end] != '\n' {
+		end++
+	}
+	if end < len(src) {
+		end++
+	}
+
+	return start, end
+}
+
+// removeToolImport removes the import of importPath from the tools file source along with its comments, returning
+// the formatted source. All other imports, comments and formatting are preserved.
+func removeToolImport(src []byte, importPath string) ([]byte, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", src, parser.ImportsOnly|parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, importDecl := range getImportDecls(file) {
+		for _, spec := range importDecl.Specs {
+			importSpec := spec.(*ast.ImportSpec)
+			if existing, _ := strconv.Unquote(importSpec.Path.Value); existing != importPath {
+				continue
+			}
+
+			var node ast.Node = importSpec
+			doc := importSpec.Doc
+			if len(importDecl.Specs) == 1 {
+				node = importDecl
+				doc = importDecl.Doc
+			}
+
+			start := fset.Position(node.Pos()).Offset
+			if doc != nil {
+				start = fset.Position(doc.Pos()).Offset
+			}
+
+			end := fset.Position(node.End()).Offset
+			if importSpec.Comment != nil && fset.Position(importSpec.Comment.End()).Offset > end {
+				end = fset.Position(importSpec.Comment.End()).Offset
+			}
+
+			start, end = getLineRange(src, start, end)
+			result := append([]byte{}, src[:start]...)
+			result = append(result, src[end:]...)
+
+			return format.Source(result)
+		}
+	}
+
+	return nil, fmt.Errorf("cant find import %s in tools file", importPath)
+}
@@ -0,0 +1,110 @@
+package gomodrun
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("toolsfile", func() {
+	Context("getToolsFile", func() {
+		It("should return the file built only with the tools tag", func() {
+			tempDir, err := ioutil.TempDir(os.TempDir(), "gomodrun-toolsfile")
+			Expect(err).To(BeNil())
+			defer os.RemoveAll(tempDir) //nolint // Test cleanup
+
+			err = ioutil.WriteFile(path.Join(tempDir, "main.go"), []byte("package app\n"), 0600)
+			Expect(err).To(BeNil())
+			err = ioutil.WriteFile(path.Join(tempDir, "tools.go"), []byte("//go:build tools\n// +build tools\n\npackage app\n"), 0600)
+			Expect(err).To(BeNil())
+
+			toolsFile, err := getToolsFile(tempDir)
+			Expect(err).To(BeNil())
+			Expect(toolsFile).To(Equal(path.Join(tempDir, "tools.go")))
+
+			pkgName, err := getToolsPkgName(tempDir)
+			Expect(err).To(BeNil())
+			Expect(pkgName).To(Equal("app"))
+		})
+
+		It("should return an empty string when there is no tools file", func() {
+			tempDir, err := ioutil.TempDir(os.TempDir(), "gomodrun-toolsfile")
+			Expect(err).To(BeNil())
+			defer os.RemoveAll(tempDir) //nolint // Test cleanup
+
+			toolsFile, err := getToolsFile(tempDir)
+			Expect(err).To(BeNil())
+			Expect(toolsFile).To(Equal(""))
+		})
+	})
+
+	Context("addToolImport", func() {
+		It("should add the import to an existing import block", func() {
+			src := "//go:build tools\n// +build tools\n\npackage app\n\nimport (\n\t// Linter.\n\t_ \"github.com/golangci/golangci-lint/cmd/golangci-lint\"\n)\n"
+			result, err := addToolImport([]byte(src), "github.com/golang/mock/mockgen")
+			Expect(err).To(BeNil())
+			Expect(string(result)).To(Equal("//go:build tools\n// +build tools\n\npackage app\n\nimport (\n\t_ \"github.com/golang/mock/mockgen\"\n\t// Linter.\n\t_ \"github.com/golangci/golangci-lint/cmd/golangci-lint\"\n)\n"))
+
+			result, err = addToolImport([]byte(src), "mvdan.cc/gofumpt")
+			Expect(err).To(BeNil())
+			Expect(string(result)).To(Equal("//go:build tools\n// +build tools\n\npackage app\n\nimport (\n\t// Linter.\n\t_ \"github.com/golangci/golangci-lint/cmd/golangci-lint\"\n\t_ \"mvdan.cc/gofumpt\"\n)\n"))
+		})
+
+		It("should add an import block when there are no imports", func() {
+			result, err := addToolImport([]byte("//go:build tools\n// +build tools\n\npackage app\n"), "github.com/golang/mock/mockgen")
+			Expect(err).To(BeNil())
+			Expect(string(result)).To(Equal("//go:build tools\n// +build tools\n\npackage app\n\nimport (\n\t_ \"github.com/golang/mock/mockgen\"\n)\n"))
+		})
+
+		It("should add after a single import", func() {
+			result, err := addToolImport([]byte("//go:build tools\n// +build tools\n\npackage app\n\nimport _ \"mvdan.cc/gofumpt\"\n"), "github.com/golang/mock/mockgen")
+			Expect(err).To(BeNil())
+			Expect(string(result)).To(Equal("//go:build tools\n// +build tools\n\npackage app\n\nimport _ \"mvdan.cc/gofumpt\"\nimport _ \"github.com/golang/mock/mockgen\"\n"))
+		})
+
+		It("should not change the source when the import exists", func() {
+			src := "package app\n\nimport (\n\t_ \"github.com/golang/mock/mockgen\"   \n)\n"
+			result, err := addToolImport([]byte(src), "github.com/golang/mock/mockgen")
+			Expect(err).To(BeNil())
+			Expect(string(result)).To(Equal(src))
+		})
+	})
+
+	Context("removeToolImport", func() {
+		It("should remove the import and its comments while preserving the rest", func() {
+			src := "//go:build tools\n// +build tools\n\npackage app\n\nimport (\n\t// Mocks.\n\t_ \"github.com/golang/mock/mockgen\" // v1 only.\n\n\t// Linter.\n\t_ \"github.com/golangci/golangci-lint/cmd/golangci-lint\"\n)\n"
+			result, err := removeToolImport([]byte(src), "github.com/golang/mock/mockgen")
+			Expect(err).To(BeNil())
+			Expect(string(result)).To(Equal("//go:build tools\n// +build tools\n\npackage app\n\nimport (\n\n\t// Linter.\n\t_ \"github.com/golangci/golangci-lint/cmd/golangci-lint\"\n)\n"))
+		})
+
+		It("should remove the import declaration when it's the only import", func() {
+			src := "//go:build tools\n// +build tools\n\npackage app\n\n// Tools.\nimport (\n\t_ \"github.com/golang/mock/mockgen\"\n)\n"
+			result, err := removeToolImport([]byte(src), "github.com/golang/mock/mockgen")
+			Expect(err).To(BeNil())
+			Expect(string(result)).To(Equal("//go:build tools\n// +build tools\n\npackage app\n"))
+		})
+
+		It("should return an error when the import does not exist", func() {
+			_, err := removeToolImport([]byte("package app\n"), "github.com/golang/mock/mockgen")
+			Expect(err).ToNot(BeNil())
+		})
+	})
+
+	Context("newToolsFile", func() {
+		It("should include both build constraints", func() {
+			result, err := newToolsFile("app", []string{"tools"}, []string{"mvdan.cc/gofumpt"})
+			Expect(err).To(BeNil())
+			Expect(string(result)).To(Equal("//go:build tools\n// +build tools\n\npackage app\n\nimport (\n\t_ \"mvdan.cc/gofumpt\"\n)\n"))
+		})
+
+		It("should require every configured tag", func() {
+			result, err := newToolsFile("app", []string{"tools", "ci"}, []string{})
+			Expect(err).To(BeNil())
+			Expect(string(result)).To(Equal("//go:build tools && ci\n// +build tools,ci\n\npackage app\n"))
+		})
+	})
+})
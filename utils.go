@@ -5,8 +5,10 @@
 package gomodrun
 
 import (
+	"fmt"
 	"go/build"
 	"io/ioutil"
+	"os"
 	"os/exec"
 	"path"
 	"strings"
@@ -34,16 +36,108 @@ func getGoMod(root string) (*modfile.File, error) {
 }
 
 func getToolsPkg(root string) (*build.Package, error) {
+	config, err := LoadConfig(root)
+	if err != nil {
+		return nil, err
+	}
+
 	importContext := build.Default
-	importContext.BuildTags = []string{"tools"}
+	importContext.BuildTags = config.Tags
 	return importContext.ImportDir(root, 0)
 }
 
 func getGoVersion() (string, error) {
-	goVersionOutput, err := exec.Command("go", "version").Output()
+	return (&goToolchain{path: "go"}).Version()
+}
+
+// getGoSum returns the module hash for modulePath at version from go.sum in root, or an empty string if go.sum
+// doesn't list it.
+func getGoSum(root, modulePath, version string) (string, error) {
+	data, err := ioutil.ReadFile(path.Join(root, "go.sum"))
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 3 && fields[0] == modulePath && fields[1] == version {
+			return fields[2], nil
+		}
+	}
+
+	return "", nil
+}
+
+// getRequire returns the go.mod require for the module providing importPath, or nil if there isn't one.
+func getRequire(mod *modfile.File, importPath string) *modfile.Require {
+	var found *modfile.Require
+	for _, req := range mod.Require {
+		if importPath != req.Mod.Path && !strings.HasPrefix(importPath, req.Mod.Path+"/") {
+			continue
+		}
+
+		if found == nil || len(req.Mod.Path) > len(found.Mod.Path) {
+			found = req
+		}
+	}
+
+	return found
+}
+
+// fileSnapshot holds the contents of files before a change, nil for files that didn't exist.
+type fileSnapshot map[string][]byte
+
+// takeSnapshot reads filePaths so they can be restored if a change fails part way through.
+func takeSnapshot(filePaths ...string) (fileSnapshot, error) {
+	snapshot := fileSnapshot{}
+	for _, filePath := range filePaths {
+		data, err := ioutil.ReadFile(filePath)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		snapshot[filePath] = data
+	}
+
+	return snapshot, nil
+}
+
+// restore writes every file back to its snapshot, removing files that didn't exist.
+func (s fileSnapshot) restore() {
+	for filePath, data := range s {
+		if data == nil {
+			os.Remove(filePath) //nolint // Ignore error, the original error is more useful.
+			continue
+		}
+		ioutil.WriteFile(filePath, data, 0o644) //nolint // Ignore error, the original error is more useful.
+	}
+}
+
+// getModSnapshot snapshots go.mod, go.sum and the given files in pkgRoot before running go commands that change them.
+func getModSnapshot(pkgRoot string, filePaths ...string) (fileSnapshot, error) {
+	return takeSnapshot(append([]string{path.Join(pkgRoot, "go.mod"), path.Join(pkgRoot, "go.sum")}, filePaths...)...)
+}
+
+// runGo runs the go command in dir, returning its combined output in the error if it fails.
+func runGo(dir string, args ...string) error {
+	cmd := exec.Command("go", args...)
+	cmd.Dir = dir
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("go %s failed: %s", strings.Join(args, " "), output)
+	}
+
+	return nil
+}
+
+// getGoEnv returns the value of a go environment variable as reported by `go env`.
+func getGoEnv(key string) (string, error) {
+	output, err := exec.Command("go", "env", key).Output()
 	if err != nil {
 		return "", err
 	}
 
-	return strings.Split(string(goVersionOutput), " ")[2], nil
+	return strings.TrimSpace(string(output)), nil
 }